@@ -0,0 +1,382 @@
+package piscator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+var appID, appInstallationID int64
+var appKeyPath, authHost, authScopes string
+var isWithToken bool
+
+// the token sources are shared by listing and cloning so an app installation
+// token is only exchanged once per owner and refreshed in one place
+var tokenSourcesMu sync.Mutex
+var tokenSources = map[string]piscator.TokenSource{}
+
+// Returns the GitHub App token source configured with --app-id, or nil when
+// piscator isn't authenticating as an app. owner is the org or user whose
+// installation is used when --app-installation-id isn't given.
+func appTokenSource(owner string) (piscator.TokenSource, error) {
+	tokenSourcesMu.Lock()
+	defer tokenSourcesMu.Unlock()
+	if source, ok := tokenSources[owner]; ok {
+		return source, nil
+	}
+
+	id := viper.GetInt64("app_id")
+	if id == 0 {
+		return nil, nil
+	}
+
+	keyPath := viper.GetString("app_private_key")
+	if keyPath == "" {
+		return nil, fmt.Errorf("--app-id requires --app-key")
+	}
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading GitHub App private key: %w", err)
+	}
+
+	client, err := transportClient()
+	if err != nil {
+		return nil, err
+	}
+	source, err := piscator.NewAppTokenSource(client, id, key, viper.GetInt64("app_installation_id"), owner)
+	if err != nil {
+		return nil, err
+	}
+	source.APIBase = piscator.APIBaseURL(enterprise)
+
+	tokenSources[owner] = source
+	return source, nil
+}
+
+// Returns the forge host git credentials are limited to
+func gitHost() string {
+	return piscator.NormalizeHost(enterprise)
+}
+
+// Wraps executor so git clones over HTTPS authenticate with the GitHub App,
+// token or basic auth credentials piscator was given. The returned func
+// removes the credential helper once the reel is done.
+func gitExecutor(owner, token string, executor piscator.RealCommandExecutor) (piscator.CommandExecutor, func(), error) {
+	source, err := appTokenSource(owner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user := "x-access-token"
+	switch {
+	case source != nil:
+	case token != "":
+		source = piscator.StaticToken(token)
+	case viper.GetString("username") != "" && viper.GetString("password") != "":
+		user = viper.GetString("username")
+		source = piscator.StaticToken(viper.GetString("password"))
+	default:
+		return executor, func() {}, nil
+	}
+
+	credentials, err := piscator.NewCredentialExecutor(executor, source, user, gitHost())
+	if err != nil {
+		return nil, nil, err
+	}
+	return credentials, func() { credentials.Close() }, nil
+}
+
+// Binds the token flags of the command being run to their env keys, so the
+// flag wins over GITHUB_TOKEN and friends for cast, reel and licenses alike
+func bindAuthFlags(cmd *cobra.Command) {
+	viper.BindPFlag("github_token", cmd.Flags().Lookup("token"))
+	viper.BindPFlag("username", cmd.Flags().Lookup("username"))
+	viper.BindPFlag("password", cmd.Flags().Lookup("password"))
+}
+
+// Returns the credential store, asking for the passphrase of the encrypted
+// file when there's no keyring and PISCATOR_PASSPHRASE isn't set. prompt asks
+// even when nothing is stored yet or stdin isn't a terminal, as logging in
+// does, otherwise it's only asked on a terminal for tokens already stored.
+func credentialStore(prompt bool) (piscator.CredentialStore, error) {
+	store, err := piscator.DefaultCredentialStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if file, ok := store.(*piscator.FileStore); ok {
+		file.Passphrase = os.Getenv("PISCATOR_PASSPHRASE")
+		_, statErr := os.Stat(file.Path)
+		if file.Passphrase == "" && (prompt || statErr == nil && term.IsTerminal(int(stdin.Fd()))) {
+			if file.Passphrase, err = readSecret("Passphrase for " + file.Path + ": "); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return store, nil
+}
+
+// stdin is read through one buffered reader, so a token and a passphrase piped
+// in one after the other each get their own line
+var stdin = os.Stdin
+var stdinLines = bufio.NewReader(stdin)
+
+// Reads a secret from the terminal without echoing it, or a line from stdin
+// when it's piped in
+func readSecret(prompt string) (string, error) {
+	fd := int(stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := stdinLines.ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	secret, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return strings.TrimSpace(string(secret)), err
+}
+
+// Returns the sources searched for a token after --token and GITHUB_TOKEN
+func credentialChain() piscator.CredentialChain {
+	return piscator.CredentialChain{
+		piscator.GhHosts{Path: piscator.DefaultGhHostsPath()},
+		piscator.Netrc{Path: piscator.DefaultNetrcPath()},
+		storedCredentials{},
+	}
+}
+
+var errPassphraseRequired = errors.New("the tokens saved with `piscator auth login` are encrypted, set PISCATOR_PASSPHRASE to their passphrase or pass --token")
+
+// storedCredentials opens the credential store only once the other sources
+// had no token, so the passphrase isn't asked for needlessly
+type storedCredentials struct{}
+
+func (storedCredentials) Credential(host string) (piscator.Credential, error) {
+	store, err := credentialStore(false)
+	if err != nil {
+		return piscator.Credential{}, err
+	}
+	return piscator.StoredCredentials{Store: store}.Credential(host)
+}
+
+// Returns the token for the forge host: --token or GITHUB_TOKEN first, then
+// gh's hosts.yml, ~/.netrc and tokens saved with `piscator auth login`.
+// Nothing is looked up when basic auth credentials were given. Fails when
+// saved tokens can't be decrypted for want of a passphrase, rather than
+// carrying on unauthenticated.
+func resolveToken(cmd *cobra.Command, logger *slog.Logger) (string, error) {
+	bindAuthFlags(cmd)
+	if token := viper.GetString("github_token"); token != "" {
+		return token, nil
+	}
+	if viper.GetString("username") != "" {
+		return "", nil
+	}
+
+	host := gitHost()
+	cred, err := credentialChain().Credential(host)
+	if errors.Is(err, piscator.ErrPassphraseRequired) {
+		return "", errPassphraseRequired
+	}
+	if err != nil {
+		if err != piscator.ErrNoCredentials {
+			logger.Warn("couldn't read every credential source", "host", host, "err", err)
+		}
+		return "", nil
+	}
+	logger.Debug("using stored credentials", "host", host, "source", cred.Source)
+	return cred.Token, nil
+}
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "manage the tokens piscator uses for each forge host",
+	Long: `A sailor guards the keys to the hold. Hand piscator a token once and it
+keeps it in your system keyring, ready for every voyage to that host.`,
+}
+
+// oauthClientID is the OAuth app used for `auth login`, set at build time
+// with -ldflags "-X github.com/shimman-dev/piscator/cmd/piscator.oauthClientID=...".
+// make build passes $OAUTH_CLIENT_ID and releases the OAUTH_CLIENT_ID
+// repository variable.
+var oauthClientID string
+
+// Logs in through the browser with the OAuth device flow
+func deviceLogin(host string) (string, error) {
+	clientID := viper.GetString("oauth_client_id")
+	if clientID == "" {
+		return "", errors.New("this build has no OAuth client id, pass --client-id or set PISCATOR_OAUTH_CLIENT_ID to your OAuth app's, or log in with a token using --with-token")
+	}
+
+	client, err := transportClient()
+	if err != nil {
+		return "", err
+	}
+	flow := &piscator.DeviceFlow{
+		Client:   client,
+		Sleeper:  &piscator.RealSleeper{},
+		BaseURL:  piscator.WebURL(host),
+		ClientID: clientID,
+		Scopes:   splitList(authScopes),
+	}
+	code, err := flow.RequestCode()
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(os.Stderr, "First copy your one-time code: %s\n", code.UserCode)
+	fmt.Fprintf(os.Stderr, "Then open %s in your browser to authorize piscator\n", code.VerificationURI)
+	return flow.PollToken(code)
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "log in to a forge host and store the token",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := piscator.NormalizeHost(authHost)
+		var token string
+		var err error
+		if isWithToken {
+			token, err = readSecret("Token for " + host + ": ")
+		} else {
+			token, err = deviceLogin(host)
+		}
+		if err != nil {
+			return err
+		}
+		if token == "" {
+			return errors.New("no token given")
+		}
+
+		store, err := credentialStore(true)
+		if err != nil {
+			return err
+		}
+		if err := store.Set(host, token); err != nil {
+			return fmt.Errorf("error storing token: %w", err)
+		}
+
+		fmt.Printf("Logged in to %s\n", host)
+		return nil
+	},
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "show which token is used for a forge host and who it belongs to",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := piscator.NormalizeHost(authHost)
+		logger, err := newLogger(os.Stderr, false)
+		if err != nil {
+			return err
+		}
+
+		source := "GITHUB_TOKEN"
+		token := os.Getenv("GITHUB_TOKEN")
+		if token == "" {
+			cred, err := credentialChain().Credential(host)
+			if err == piscator.ErrNoCredentials {
+				return fmt.Errorf("not logged in to %s", host)
+			}
+			if errors.Is(err, piscator.ErrPassphraseRequired) {
+				return errPassphraseRequired
+			}
+			if err != nil {
+				return err
+			}
+			source, token = cred.Source, cred.Token
+		}
+
+		client, err := transportClient()
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequest("GET", piscator.APIBaseURL(host)+"/user", nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("User-Agent", "shimman-dev/piscator")
+		res, err := piscator.NewRetryClient(&piscator.AuthClient{Client: client, Source: piscator.StaticToken(token)}, &piscator.RealSleeper{}, piscator.NewLogObserver(logger)).Do(req)
+		if err != nil {
+			return fmt.Errorf("the token from %s doesn't work: %w", source, err)
+		}
+		defer res.Body.Close()
+
+		var user struct {
+			Login string `json:"login"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&user); err != nil {
+			return err
+		}
+
+		fmt.Printf("Logged in to %s as %s\n", host, user.Login)
+		fmt.Printf("Token from %s\n", source)
+		if scopes := res.Header.Get("X-OAuth-Scopes"); scopes != "" {
+			fmt.Printf("Scopes: %s\n", scopes)
+		}
+		return nil
+	},
+}
+
+var authLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "remove the stored token for a forge host",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		host := piscator.NormalizeHost(authHost)
+		store, err := credentialStore(true)
+		if err != nil {
+			return err
+		}
+		if err := store.Delete(host); err != nil {
+			if errors.Is(err, piscator.ErrNoCredentials) {
+				return fmt.Errorf("no token stored for %s", host)
+			}
+			return err
+		}
+
+		fmt.Printf("Logged out of %s\n", host)
+		return nil
+	},
+}
+
+func init() {
+	authCmd.PersistentFlags().StringVar(&authHost, "host", "github.com", "Forge host to log in to")
+	authLoginCmd.Flags().BoolVar(&isWithToken, "with-token", false, "Read a token from stdin instead of logging in through the browser")
+	authLoginCmd.Flags().StringVar(&authScopes, "scopes", "repo,read:org", "OAuth scopes to request")
+	authLoginCmd.Flags().String("client-id", "", "OAuth app client id used for the device flow")
+
+	viper.BindPFlag("oauth_client_id", authLoginCmd.Flags().Lookup("client-id"))
+	viper.BindEnv("oauth_client_id", "PISCATOR_OAUTH_CLIENT_ID")
+	viper.SetDefault("oauth_client_id", oauthClientID)
+
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	rootCmd.AddCommand(authCmd)
+
+	rootCmd.PersistentFlags().Int64Var(&appID, "app-id", 0, "Authenticate as this GitHub App")
+	rootCmd.PersistentFlags().StringVar(&appKeyPath, "app-key", "", "Path to the GitHub App private key (PEM)")
+	rootCmd.PersistentFlags().Int64Var(&appInstallationID, "app-installation-id", 0, "GitHub App installation id, looked up for the org/user when omitted")
+
+	viper.BindPFlag("app_id", rootCmd.PersistentFlags().Lookup("app-id"))
+	viper.BindPFlag("app_private_key", rootCmd.PersistentFlags().Lookup("app-key"))
+	viper.BindPFlag("app_installation_id", rootCmd.PersistentFlags().Lookup("app-installation-id"))
+
+	viper.BindEnv("app_id", "GITHUB_APP_ID")
+	viper.BindEnv("app_private_key", "GITHUB_APP_PRIVATE_KEY_PATH")
+	viper.BindEnv("app_installation_id", "GITHUB_APP_INSTALLATION_ID")
+}
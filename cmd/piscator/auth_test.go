@@ -0,0 +1,33 @@
+package piscator
+
+import (
+	"bufio"
+	"os"
+	"testing"
+)
+
+func TestReadSecretSharesPipedStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if _, err := w.WriteString("gho_token\ncorrect horse\n"); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	oldStdin, oldLines := stdin, stdinLines
+	stdin, stdinLines = r, bufio.NewReader(r)
+	defer func() { stdin, stdinLines = oldStdin, oldLines }()
+
+	for _, want := range []string{"gho_token", "correct horse"} {
+		got, err := readSecret("")
+		if err != nil {
+			t.Fatalf("readSecret() error = %v", err)
+		}
+		if got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package piscator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var isBranchCreate, isBranchCheckout, isBranchWorktree, isBranchPush bool
+var branchBase, branchRemote string
+var branchRepos []string
+
+func branchRun(cmd *cobra.Command, args []string) error {
+	dir, branch := args[0], args[1]
+	if isBranchCreate == isBranchCheckout {
+		return fmt.Errorf("pass exactly one of --create or --checkout")
+	}
+	if branchBase != "" && !isBranchCreate {
+		return fmt.Errorf("--base only applies to --create")
+	}
+
+	repos, err := piscator.FindRepos(dir)
+	if err != nil {
+		return err
+	}
+	repos, err = matchRepos(repos, branchRepos)
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("no repos found under %s", dir)
+	}
+
+	var executor piscator.CommandExecutor = piscator.RealCommandExecutor{Env: tlsConfig.GitEnv()}
+	if isBranchPush {
+		logger, err := newLogger(os.Stderr, false)
+		if err != nil {
+			return err
+		}
+		token, err := resolveToken(cmd, logger)
+		if err != nil {
+			return err
+		}
+		credentials, cleanup, err := pushExecutor(dir, repos, token)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+		executor = credentials
+	}
+
+	opts := piscator.BranchOptions{
+		Branch:   branch,
+		Create:   isBranchCreate,
+		Base:     branchBase,
+		Worktree: isBranchWorktree,
+		Push:     isBranchPush,
+		Remote:   branchRemote,
+	}
+	results := piscator.BranchRepos(executor, dir, repos, opts, 10)
+	fmt.Print(piscator.FormatBranchResults(results, opts))
+
+	for _, result := range results {
+		if result.Err != nil {
+			return fmt.Errorf("%s failed in some repos", branch)
+		}
+	}
+	return nil
+}
+
+// repoExecutors runs the commands of each repo with its own executor, keyed by
+// the repo's path, and any other command with fallback
+type repoExecutors struct {
+	byDir    map[string]piscator.CommandExecutor
+	fallback piscator.CommandExecutor
+}
+
+func (r repoExecutors) ExecuteCommand(name string, arg ...string) ([]byte, error) {
+	return r.fallback.ExecuteCommand(name, arg...)
+}
+
+func (r repoExecutors) ExecuteCommandInDir(dir, name string, arg ...string) ([]byte, error) {
+	if executor, ok := r.byDir[dir]; ok {
+		return executor.ExecuteCommandInDir(dir, name, arg...)
+	}
+	return r.fallback.ExecuteCommandInDir(dir, name, arg...)
+}
+
+// Returns the executor pushing the repos under dir. A GitHub App pushes each
+// repo with the installation of the owner in its remote's URL, since stars,
+// search results and --input lists are reeled from several owners side by
+// side.
+func pushExecutor(dir string, repos []string, token string) (piscator.CommandExecutor, func(), error) {
+	base := piscator.RealCommandExecutor{Env: tlsConfig.GitEnv()}
+	if viper.GetInt64("app_id") == 0 || viper.GetInt64("app_installation_id") != 0 {
+		return gitExecutor("", token, base)
+	}
+
+	remote := branchRemote
+	if remote == "" {
+		remote = "origin"
+	}
+
+	owners := map[string]piscator.CommandExecutor{}
+	var cleanups []func()
+	cleanup := func() {
+		for _, c := range cleanups {
+			c()
+		}
+	}
+	executors := repoExecutors{byDir: map[string]piscator.CommandExecutor{}, fallback: base}
+	for _, repo := range repos {
+		repoPath := filepath.Join(dir, filepath.FromSlash(repo))
+		// repos without the remote are left to fail their push
+		out, err := base.ExecuteCommandInDir(repoPath, "git", "remote", "get-url", remote)
+		if err != nil {
+			continue
+		}
+		owner := piscator.RepoOwnerFromURL(strings.TrimSpace(string(out)))
+		executor, ok := owners[owner]
+		if !ok {
+			var c func()
+			if executor, c, err = gitExecutor(owner, token, base); err != nil {
+				cleanup()
+				return nil, nil, fmt.Errorf("%s: %w", repo, err)
+			}
+			owners[owner] = executor
+			cleanups = append(cleanups, c)
+		}
+		executors.byDir[repoPath] = executor
+	}
+	return executors, cleanup, nil
+}
+
+// Keeps the repos matching any of the name globs, every repo when there are
+// none
+func matchRepos(repos, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		return repos, nil
+	}
+	var matched []string
+	for _, repo := range repos {
+		for _, pattern := range patterns {
+			ok, err := path.Match(pattern, repo)
+			if err != nil {
+				return nil, fmt.Errorf("invalid repo pattern %q: %w", pattern, err)
+			}
+			if ok {
+				matched = append(matched, repo)
+				break
+			}
+		}
+	}
+	return matched, nil
+}
+
+var branchCmd = &cobra.Command{
+	Use:     "branch dir branch",
+	Aliases: []string{"b"},
+	Short:   "create or check out a branch across reeled repos",
+	Long: `Hoist the same colours on every mast! The branch command sails through each
+repository reeled into a directory and creates or checks out one branch in all
+of them, or moors it in a worktree beside each repository so the main deck stays
+untouched. Repositories lacking the base are called out, and with --push every
+branch is sent back to port at once.`,
+	Args:          cobra.ExactArgs(2),
+	RunE:          branchRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	branchCmd.PersistentFlags().BoolVar(&isBranchCreate, "create", false, "Create the branch from --base, or each repo's HEAD")
+	branchCmd.PersistentFlags().BoolVar(&isBranchCheckout, "checkout", false, "Check out an existing local or remote branch")
+	branchCmd.PersistentFlags().StringVar(&branchBase, "base", "", "Branch to create the branch from")
+	branchCmd.PersistentFlags().BoolVar(&isBranchWorktree, "worktree", false, "Check the branch out in <repo>.worktrees/<branch> instead of switching the repo")
+	branchCmd.PersistentFlags().BoolVar(&isBranchPush, "push", false, "Push the branch and set its upstream")
+	branchCmd.PersistentFlags().StringVar(&branchRemote, "remote", "origin", "Remote to look up branches on and push to")
+	branchCmd.PersistentFlags().StringSliceVar(&branchRepos, "repos", nil, "Only the repos matching these name globs")
+
+	branchCmd.PersistentFlags().StringVarP(&githubToken, "token", "t", "", "GitHub personal access token")
+	branchCmd.PersistentFlags().StringVar(&enterprise, "host", "", "GitHub Enterprise Server host to push to")
+
+	rootCmd.AddCommand(branchCmd)
+}
@@ -0,0 +1,53 @@
+package piscator
+
+import (
+	"fmt"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "manage the GitHub API response cache",
+	Long: `Every sailor keeps a logbook. Piscator remembers what GitHub told it on
+previous voyages and only asks what changed since, sparing your rate limit
+when the waters are calm.`,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "remove every cached GitHub API response",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := piscator.DefaultCacheDir()
+		if err != nil {
+			return err
+		}
+		if err := piscator.ClearCache(dir); err != nil {
+			return err
+		}
+
+		fmt.Printf("Cleared %s\n", dir)
+		return nil
+	},
+}
+
+var cacheDirCmd = &cobra.Command{
+	Use:   "dir",
+	Short: "print the cache directory",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := piscator.DefaultCacheDir()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(dir)
+		return nil
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheDirCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
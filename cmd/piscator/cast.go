@@ -1,8 +1,11 @@
 package piscator
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/shimman-dev/piscator/pkg/piscator"
 	"github.com/spf13/cobra"
@@ -11,38 +14,118 @@ import (
 
 var isSelfBool, isOrgBool, isForkedBool, makeFileBool bool
 var languageFilter, name, githubToken, username, password, enterprise string
+var sinceSnapshot string
+var outputFormat, outputTemplate, outputFields, outputFile string
 
 func castRun(cmd *cobra.Command, args []string) {
-	if len(args) < 1 {
-		fmt.Println("Please provide a GitHub username")
-		return
-	}
-
-	name := args[0]
-	tokenFileBool := viper.GetString("github_token") // get token from viper
 	isSelfBool, _ := cmd.PersistentFlags().GetBool("self")
 	isOrgBool, _ := cmd.PersistentFlags().GetBool("org")
 	isForkedBool, _ := cmd.PersistentFlags().GetBool("forked")
 	makeFileBool, _ := cmd.PersistentFlags().GetBool("makeFile")
 
-	sleeper := &piscator.RealSleeper{}
+	if err := checkOutputFlags(cmd); err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
 
-	res, err := piscator.GetRepos(http.DefaultClient, sleeper, name, tokenFileBool, username, password, enterprise, isSelfBool, isOrgBool, isForkedBool, makeFileBool)
+	targets, err := collectTargets(args, isOrgBool)
+	if err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
+	// a search query lists repos across owners, no name is needed
+	if len(targets) == 0 && searchQuery == "" {
+		fmt.Println("Please provide a GitHub username")
+		return
+	}
+
+	logger, err := newLogger(os.Stderr, false)
+	if err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
 
+	token, err := resolveToken(cmd, logger)
+	if err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
+	results, err := fetchTargets(piscator.NewLogObserver(logger), targets, token, isSelfBool, isForkedBool, makeFileBool)
 	if err != nil {
 		fmt.Printf("Errors: %s", err)
 		return
 	}
 
-	if languageFilter != "" {
-		res, err = piscator.RepoByLanguage(res, languageFilter)
+	// the output is only worth printing when every target was listed
+	var listErrs []error
+	for _, result := range results {
+		listErrs = append(listErrs, result.Err)
+	}
+	if err := errors.Join(listErrs...); err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
+
+	res, err := piscator.MergeTargetRepos(results)
+	if err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
+
+	if sinceSnapshot != "" {
+		snapshot, err := os.ReadFile(sinceSnapshot)
 		if err != nil {
-			fmt.Printf("Error filtering repositories by language: %s", err)
+			fmt.Printf("Error reading snapshot: %s", err)
 			return
 		}
+		if err := printChanges(string(snapshot), res, isDiffJSON); err != nil {
+			fmt.Printf("Error comparing snapshots: %s", err)
+		}
+		return
+	}
+
+	if err := printRepos(cmd, res, targets); err != nil {
+		fmt.Printf("Errors: %s", err)
+	}
+}
+
+// Checks the output flags before a long listing, writing no repos with them
+func checkOutputFlags(cmd *cobra.Command) error {
+	switch {
+	case outputTemplate != "" && cmd.Flags().Changed("output"):
+		return fmt.Errorf("--template can't be combined with --output")
+	case outputTemplate != "" && isMetadata:
+		return fmt.Errorf("--template can't be combined with --metadata")
+	case isMetadata:
+		return piscator.WriteReposWithMetadata(io.Discard, "[]", outputFormat, splitList(outputFields), piscator.RepoFileMetadata{})
+	default:
+		return piscator.WriteRepos(io.Discard, "[]", outputFormat, splitList(outputFields))
+	}
+}
+
+// Prints repos in the --output format, or through --template, to stdout or
+// --output-file
+func printRepos(cmd *cobra.Command, res string, targets []piscator.Target) error {
+	var write func(w io.Writer) error
+	switch {
+	case outputTemplate != "":
+		write = func(w io.Writer) error {
+			return piscator.WriteReposTemplate(w, res, outputTemplate)
+		}
+	case isMetadata:
+		write = func(w io.Writer) error {
+			return piscator.WriteReposWithMetadata(w, res, outputFormat, splitList(outputFields), repoFileMetadata(targets))
+		}
+	default:
+		write = func(w io.Writer) error {
+			return piscator.WriteRepos(w, res, outputFormat, splitList(outputFields))
+		}
 	}
 
-	fmt.Println(res)
+	if outputFile == "" {
+		return write(os.Stdout)
+	}
+	return piscator.WriteFileAtomic(outputFile, write)
 }
 
 var castCmd = &cobra.Command{
@@ -54,7 +137,7 @@ exploration with the cast command. Cast your net wide and capture the URLs of
 repositories belonging to a user or organization, gathering a bountiful
 collection of code treasures. Navigate with ease, discovering new horizons, and
 charting your course towards software mastery.`,
-	Args: cobra.MinimumNArgs(1),
+	Args: targetArgs,
 	Run:  castRun,
 }
 
@@ -67,17 +150,26 @@ func init() {
 	castCmd.PersistentFlags().BoolVarP(&makeFileBool, "makeFile", "f", false, "Generate a repos.json file")
 
 	castCmd.PersistentFlags().StringVarP(&languageFilter, "language", "l", "", "Filter repositories by language(s)")
+	addListFlags(castCmd)
+	addSourceFlags(castCmd)
+	addTargetFlags(castCmd)
+	castCmd.PersistentFlags().StringVar(&apiBackend, "api", "rest", "GitHub API used to list repos: rest or graphql")
+	castCmd.PersistentFlags().StringVar(&sinceSnapshot, "since-snapshot", "", "Print changes since a previous cast snapshot instead of the repos")
+	castCmd.PersistentFlags().BoolVarP(&isDiffJSON, "json", "j", false, "Output the --since-snapshot changelog as JSON")
+	castCmd.PersistentFlags().StringVar(&outputFormat, "output", "json", "Output format: "+strings.Join(piscator.OutputFormats, ", "))
+	castCmd.PersistentFlags().StringVar(&outputTemplate, "template", "", "Print each repo with a Go template, e.g. '{{.Name}} {{.URL}}'")
+	castCmd.PersistentFlags().StringVar(&outputFields, "fields", "", "Comma separated fields to print, e.g. name,url,language")
+	castCmd.PersistentFlags().StringVar(&outputFile, "output-file", "", "Write the output to a file instead of stdout, replacing it atomically")
+	castCmd.PersistentFlags().BoolVar(&isMetadata, "metadata", false, "Start the output with its source, time, piscator version and filters")
 
 	castCmd.PersistentFlags().StringVarP(&githubToken, "token", "t", "", "GitHub personal access token")
 	castCmd.PersistentFlags().StringVarP(&username, "username", "u", "", "GitHub username")
 	castCmd.PersistentFlags().StringVarP(&password, "password", "p", "", "GitHub password")
+	castCmd.PersistentFlags().StringVar(&enterprise, "host", "", "GitHub Enterprise Server host to list and clone from")
 	castCmd.PersistentFlags().StringVarP(&enterprise, "enterprise", "e", "", "GitHub Enterprise URL")
+	castCmd.PersistentFlags().MarkDeprecated("enterprise", "use --host instead")
 
-	// bind the token flags to env keys
-	viper.BindPFlag("github_token", castCmd.PersistentFlags().Lookup("token"))
-	viper.BindPFlag("username", castCmd.PersistentFlags().Lookup("username"))
-	viper.BindPFlag("password", castCmd.PersistentFlags().Lookup("password"))
-
+	// the token flags are bound to env keys by the running command, see resolveToken
 	viper.BindEnv("github_token", "GITHUB_TOKEN")
 	viper.BindEnv("username", "GITHUB_USERNAME")
 	viper.BindEnv("password", "GITHUB_PASSWORD")
@@ -0,0 +1,65 @@
+package piscator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+)
+
+var isDiffJSON bool
+
+// Prints the changes between two repo listings as text or JSON
+func printChanges(oldJSON, newJSON string, asJSON bool) error {
+	changes, err := piscator.DiffRepos(oldJSON, newJSON)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		jsonData, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Print(piscator.FormatChanges(changes))
+	return nil
+}
+
+func diffRun(cmd *cobra.Command, args []string) error {
+	oldJSON, err := os.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+	newJSON, err := os.ReadFile(args[1])
+	if err != nil {
+		return err
+	}
+
+	return printChanges(string(oldJSON), string(newJSON), isDiffJSON)
+}
+
+var diffCmd = &cobra.Command{
+	Use:     "diff old.json new.json",
+	Aliases: []string{"d"},
+	Short:   "compare two cast snapshots",
+	Long: `Land ho! Lay two charts of the same waters side by side and the diff command
+marks every change to the coastline: repositories that surfaced, sank beneath
+the waves, changed their names, hoisted a private flag, ran aground in the
+archives or switched the tongue they speak.`,
+	Args:          cobra.ExactArgs(2),
+	RunE:          diffRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	diffCmd.PersistentFlags().BoolVarP(&isDiffJSON, "json", "j", false, "Output the changelog as JSON")
+
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,67 @@
+package piscator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+)
+
+var exportFormat, exportDir, exportFile string
+
+func exportRun(cmd *cobra.Command, args []string) error {
+	// check the format before waiting on stdin
+	if err := piscator.ExportRepos(io.Discard, nil, exportFormat, exportDir); err != nil {
+		return err
+	}
+
+	// the listing is read from stdin unless a file is given
+	var r io.Reader = os.Stdin
+	if len(args) == 1 && args[0] != "-" {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	repos, err := piscator.ReadRepoList(r)
+	if err != nil {
+		return err
+	}
+
+	write := func(w io.Writer) error {
+		return piscator.ExportRepos(w, repos, exportFormat, exportDir)
+	}
+	if exportFile == "" {
+		return write(os.Stdout)
+	}
+	return piscator.WriteFileAtomic(exportFile, write)
+}
+
+var exportCmd = &cobra.Command{
+	Use:     "export [repos.json]",
+	Aliases: []string{"e"},
+	Short:   "convert a cast listing into another multi-repo tool's config",
+	Long: `Ahoy, navigator! Not every crew reads the same charts. The export command
+redraws a cast listing in the tongue of another fleet, be it an Android repo
+manifest, a superproject's .gitmodules, a myrepos .mrconfig or a gita roster,
+so your haul can sail on with whichever tools your shipmates swear by.`,
+	Args:          cobra.MaximumNArgs(1),
+	RunE:          exportRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	exportCmd.PersistentFlags().StringVar(&exportFormat, "format", "", fmt.Sprintf("Config to write: %s", strings.Join(piscator.ExportFormats, ", ")))
+	exportCmd.PersistentFlags().StringVar(&exportDir, "dir", "", "Directory the repos are placed under, e.g. the one they were reeled into")
+	exportCmd.PersistentFlags().StringVar(&exportFile, "output-file", "", "Write the config to a file instead of stdout, replacing it atomically")
+	exportCmd.MarkPersistentFlagRequired("format")
+
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,150 @@
+package piscator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+)
+
+var grepLanguages, grepReposFile string
+var grepFilters, grepRepos []string
+var isGrepJSON, isGrepFiles, isGrepIgnoreCase, isGrepFixed, isGrepBuiltin bool
+
+func grepRun(cmd *cobra.Command, args []string) error {
+	dir, pattern := args[0], args[1]
+
+	repos, err := piscator.FindRepos(dir)
+	if err != nil {
+		return err
+	}
+	repos, err = matchRepos(repos, grepRepos)
+	if err != nil {
+		return err
+	}
+	if grepLanguages != "" || len(grepFilters) > 0 {
+		if repos, err = filterGrepRepos(dir, repos); err != nil {
+			return err
+		}
+		if len(repos) == 0 {
+			return fmt.Errorf("no repos under %s match --lang and --filter", dir)
+		}
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("no repos found under %s", dir)
+	}
+
+	opts := piscator.GrepOptions{
+		Pattern:          pattern,
+		IgnoreCase:       isGrepIgnoreCase,
+		FixedStrings:     isGrepFixed,
+		FilesWithMatches: isGrepFiles,
+		Builtin:          isGrepBuiltin,
+	}
+	// hosts without git can still search what was reeled
+	if _, err := exec.LookPath("git"); err != nil {
+		opts.Builtin = true
+	}
+	results, err := piscator.GrepRepos(piscator.RealCommandExecutor{}, dir, repos, opts, 10)
+	if err != nil {
+		return err
+	}
+
+	if isGrepJSON {
+		err = piscator.WriteGrepJSON(os.Stdout, results)
+	} else {
+		err = piscator.WriteGrepResults(os.Stdout, results, isGrepFiles)
+	}
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Repo, result.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Narrows the repos to those whose listing matches --lang and --filter. The
+// listing is --repos-file, or the repos.json reel -f wrote in the directory or
+// next to it.
+func filterGrepRepos(dir string, repos []string) ([]string, error) {
+	name := grepReposFile
+	if name == "" {
+		for _, candidate := range []string{filepath.Join(dir, "repos.json"), "repos.json"} {
+			if _, err := os.Stat(candidate); err == nil {
+				name = candidate
+				break
+			}
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("--lang and --filter need the repos' listing, write it with reel -f or pass --repos-file")
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	listed, err := piscator.ReadRepoList(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if grepLanguages != "" {
+		data, err := json.Marshal(listed)
+		if err != nil {
+			return nil, err
+		}
+		res, err := piscator.RepoByLanguage(string(data), grepLanguages)
+		if err != nil {
+			return nil, err
+		}
+		listed = nil
+		if err := json.Unmarshal([]byte(res), &listed); err != nil {
+			return nil, err
+		}
+	}
+	if listed, err = piscator.FilterRepos(listed, grepFilters); err != nil {
+		return nil, err
+	}
+	return piscator.ListedRepos(repos, listed), nil
+}
+
+var grepCmd = &cobra.Command{
+	Use:     "grep dir pattern",
+	Aliases: []string{"g"},
+	Short:   "search the reeled repos with git grep",
+	Long: `Sound the depths! The grep command drops a line into every repository reeled
+into a directory at once and hauls up each line matching your pattern, sorted
+by the repository it came from and tallied as it lands. Choose your waters by
+language or any other mark the listing recorded, and take the catch as plain
+text, JSON or just the names of the files that bit.`,
+	Args:          cobra.ExactArgs(2),
+	RunE:          grepRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	grepCmd.PersistentFlags().StringVar(&grepLanguages, "lang", "", "Only search repos in these language(s)")
+	grepCmd.PersistentFlags().StringArrayVar(&grepFilters, "filter", nil, "Only search repos whose field=value, such as visibility=private or topics=cli")
+	grepCmd.PersistentFlags().StringVar(&grepReposFile, "repos-file", "", "Listing --lang and --filter read, repos.json in or beside dir by default")
+	grepCmd.PersistentFlags().StringSliceVar(&grepRepos, "repos", nil, "Only the repos matching these name globs")
+	grepCmd.PersistentFlags().BoolVarP(&isGrepFiles, "files-with-matches", "l", false, "Print the names of matching files instead of lines")
+	grepCmd.PersistentFlags().BoolVar(&isGrepJSON, "json", false, "Print the results as JSON")
+	grepCmd.PersistentFlags().BoolVarP(&isGrepIgnoreCase, "ignore-case", "i", false, "Match case-insensitively")
+	grepCmd.PersistentFlags().BoolVarP(&isGrepFixed, "fixed-strings", "F", false, "Match the pattern literally instead of as a regular expression")
+	grepCmd.PersistentFlags().BoolVar(&isGrepBuiltin, "builtin", false, "Search the working trees without git, untracked files included")
+
+	rootCmd.AddCommand(grepCmd)
+}
@@ -0,0 +1,133 @@
+package piscator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var licenseDir, licenseAllow string
+var isLicenseJSON bool
+
+func licensesRun(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	logger, err := newLogger(os.Stderr, false)
+	if err != nil {
+		return err
+	}
+
+	token, err := resolveToken(cmd, logger)
+	if err != nil {
+		return err
+	}
+	res, err := fetchRepos(piscator.NewLogObserver(logger), name, token, isSelfBool, isOrgBool, isForkedBool)
+	if err != nil {
+		return err
+	}
+
+	if languageFilter != "" {
+		res, err = piscator.RepoByLanguage(res, languageFilter)
+		if err != nil {
+			return fmt.Errorf("error filtering repositories by language: %w", err)
+		}
+	}
+
+	// reel clones into a directory named after the user/org
+	dir := licenseDir
+	if dir == "" {
+		dir = name
+	}
+
+	var allowlist []string
+	if licenseAllow != "" {
+		allowlist = splitList(licenseAllow)
+	}
+
+	reports, err := piscator.AuditLicenses(res, dir, allowlist)
+	if err != nil {
+		return err
+	}
+
+	if isLicenseJSON {
+		jsonData, err := json.MarshalIndent(reports, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tAPI\tFILE\tSTATUS\tALLOWED")
+		for _, r := range reports {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\n", r.Name, orDash(r.APILicense), orDash(r.FileLicense), r.Status, r.Allowed)
+		}
+		w.Flush()
+	}
+
+	var disallowed int
+	for _, r := range reports {
+		if !r.Allowed {
+			disallowed++
+		}
+	}
+	if disallowed > 0 {
+		return fmt.Errorf("%d repos have a license outside the allowlist", disallowed)
+	}
+
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+var licensesCmd = &cobra.Command{
+	Use:     "licenses",
+	Aliases: []string{"l"},
+	Short:   "audit the licenses of GitHub repos",
+	Long: `Hark, quartermaster! Before any treasure is stowed below deck, the ship's
+articles must be in order. The licenses command inspects the colors flown by
+every repository, comparing what GitHub reports with the LICENSE file found in
+your reeled copies, and raises the alarm when a vessel sails without papers or
+under a flag outside your allowlist.`,
+	Args:          cobra.MinimumNArgs(1),
+	RunE:          licensesRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	viper.AutomaticEnv()
+
+	licensesCmd.PersistentFlags().BoolVarP(&isSelfBool, "self", "s", false, "Your GitHub user, requires a personal access token")
+	licensesCmd.PersistentFlags().BoolVarP(&isOrgBool, "org", "o", false, "Is an organization")
+	licensesCmd.PersistentFlags().BoolVarP(&isForkedBool, "forked", "x", false, "Include forked repositories")
+	licensesCmd.PersistentFlags().BoolVarP(&isLicenseJSON, "json", "j", false, "Output the audit as JSON")
+
+	licensesCmd.PersistentFlags().StringVarP(&languageFilter, "language", "l", "", "Filter repositories by language(s)")
+	addListFlags(licensesCmd)
+	licensesCmd.PersistentFlags().StringVar(&apiBackend, "api", "rest", "GitHub API used to list repos: rest or graphql")
+	licensesCmd.PersistentFlags().StringVarP(&licenseDir, "dir", "d", "", "Directory of reeled repos, defaults to the user/org name")
+	licensesCmd.PersistentFlags().StringVarP(&licenseAllow, "allow", "a", "", "Comma separated SPDX ids, fails when a repo's license isn't listed")
+
+	licensesCmd.PersistentFlags().StringVarP(&githubToken, "token", "t", "", "GitHub personal access token")
+	licensesCmd.PersistentFlags().StringVarP(&username, "username", "u", "", "GitHub username")
+	licensesCmd.PersistentFlags().StringVarP(&password, "password", "p", "", "GitHub password")
+	licensesCmd.PersistentFlags().StringVar(&enterprise, "host", "", "GitHub Enterprise Server host to list and clone from")
+	licensesCmd.PersistentFlags().StringVarP(&enterprise, "enterprise", "e", "", "GitHub Enterprise URL")
+	licensesCmd.PersistentFlags().MarkDeprecated("enterprise", "use --host instead")
+
+	viper.BindEnv("github_token", "GITHUB_TOKEN")
+	viper.BindEnv("username", "GITHUB_USERNAME")
+	viper.BindEnv("password", "GITHUB_PASSWORD")
+
+	rootCmd.AddCommand(licensesCmd)
+}
@@ -0,0 +1,280 @@
+package piscator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var apiBackend string
+var listTeam, listAffiliation, listVisibility, listType string
+var isStarred, isGists bool
+var searchQuery string
+var isMetadata bool
+var targetsFile string
+
+// how many targets are listed at once
+const targetListLimit = 4
+
+// every listing of a run draws from one rate limit budget, so concurrent
+// targets wait for the same reset instead of each running into the limit
+var listBudget = &piscator.RateBudget{}
+
+// Adds the flags narrowing a listing to a team, affiliation or repo type
+func addListFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&listTeam, "team", "", "Only list the repos of this org team (slug)")
+	cmd.PersistentFlags().StringVar(&listAffiliation, "affiliation", "", "With --self, repos you're an owner, collaborator or organization_member of")
+	cmd.PersistentFlags().StringVar(&listVisibility, "visibility", "", "With --self, list all, public or private repos")
+	cmd.PersistentFlags().StringVar(&listType, "type", "", "With --org, list sources, forks, member or internal repos")
+}
+
+// Adds the flags listing stars and gists instead of owned repos
+func addSourceFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&isStarred, "starred", false, "List the repos the user starred, most recent first")
+	cmd.PersistentFlags().BoolVar(&isGists, "gists", false, "List the user's gists, reel clones them into gists/")
+	cmd.PersistentFlags().StringVar(&searchQuery, "search", "", "List the repos matching a GitHub search query")
+}
+
+// Adds the flag reading more targets from a file
+func addTargetFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&targetsFile, "from-file", "", "Read more users/orgs from a file, one per line, - for stdin")
+}
+
+// Requires a user or org name unless they're read from a file, repos are
+// searched for or reel is given a repo list
+func targetArgs(cmd *cobra.Command, args []string) error {
+	if searchQuery != "" || targetsFile != "" || repoInput != "" {
+		return nil
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
+// Returns the targets given as arguments followed by those in --from-file
+func collectTargets(args []string, isOrg bool) ([]piscator.Target, error) {
+	names := append([]string{}, args...)
+	if targetsFile != "" {
+		var r io.Reader = os.Stdin
+		if targetsFile != "-" {
+			f, err := os.Open(targetsFile)
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			r = f
+		}
+		more, err := piscator.ReadTargets(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", targetsFile, err)
+		}
+		names = append(names, more...)
+	}
+
+	var targets []piscator.Target
+	for _, name := range names {
+		targets = append(targets, piscator.ParseTarget(name, isOrg))
+	}
+
+	switch {
+	case searchQuery != "" && len(targets) > 1:
+		return nil, errors.New("--search takes at most one name, the directory reel clones into")
+	case isSelfBool && len(targets) > 1:
+		return nil, errors.New("--self lists a single user, drop the other targets")
+	}
+	return targets, nil
+}
+
+// Lists every target concurrently, dropping repos already listed for an
+// earlier target and filtering them by --language. When makeFile the combined
+// listing is written to repos.json, with metadata when --metadata is given.
+func fetchTargets(observer piscator.Observer, targets []piscator.Target, token string, isSelf, isForked, makeFile bool) ([]piscator.TargetRepos, error) {
+	// search needs no name, cast lists it with an empty target
+	if len(targets) == 0 {
+		targets = []piscator.Target{{}}
+	}
+
+	results := piscator.ListTargets(targets, targetListLimit, func(target piscator.Target) (string, error) {
+		res, err := fetchRepos(observer, target.Name, token, isSelf, target.IsOrg, isForked)
+		if err != nil || languageFilter == "" {
+			return res, err
+		}
+		return piscator.RepoByLanguage(res, languageFilter)
+	})
+
+	if makeFile {
+		res, err := piscator.MergeTargetRepos(results)
+		if err != nil {
+			return nil, err
+		}
+		err = piscator.WriteFileAtomic("repos.json", func(w io.Writer) error {
+			if isMetadata {
+				return piscator.WriteReposWithMetadata(w, res, "json", nil, repoFileMetadata(targets))
+			}
+			return piscator.WriteRepos(w, res, "json", nil)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// Returns the ListOptions given with the list flags
+func listOptions() piscator.ListOptions {
+	return piscator.ListOptions{
+		Team:        listTeam,
+		Affiliation: splitList(listAffiliation),
+		Visibility:  listVisibility,
+		Type:        listType,
+	}
+}
+
+// Lists repositories through the API chosen with --api
+func fetchRepos(observer piscator.Observer, name, token string, isSelf, isOrg, isForked bool) (string, error) {
+	sleeper := &piscator.RealSleeper{}
+	client, err := newHTTPClient()
+	if err != nil {
+		return "", err
+	}
+
+	// a GitHub App token replaces any other credentials and is attached to
+	// every request so it can be refreshed mid listing
+	source, err := appTokenSource(name)
+	if err != nil {
+		return "", err
+	}
+	if source != nil {
+		if token, err = source.Token(); err != nil {
+			return "", err
+		}
+		client = &piscator.AuthClient{Client: client, Source: source}
+	}
+
+	rc := piscator.NewRetryClient(client, sleeper, observer)
+	rc.Budget = listBudget
+	client = rc
+
+	if searchQuery != "" {
+		if apiBackend != "rest" {
+			return "", fmt.Errorf("--search needs the REST API")
+		}
+		if isStarred || isGists {
+			return "", fmt.Errorf("--search can't be combined with --starred or --gists")
+		}
+		return piscator.SearchRepos(client, sleeper, observer, searchQuery, token, enterprise, isForked)
+	}
+
+	if isStarred || isGists {
+		if apiBackend != "rest" {
+			return "", fmt.Errorf("--starred and --gists need the REST API")
+		}
+		return fetchStarsAndGists(client, sleeper, observer, name, token, isSelf, isForked)
+	}
+
+	switch apiBackend {
+	case "rest":
+		return piscator.GetRepos(client, sleeper, observer, name, token, viper.GetString("username"), viper.GetString("password"), enterprise, isSelf, isOrg, isForked, listOptions())
+	case "graphql":
+		return piscator.GetReposGraphQL(client, sleeper, observer, name, token, enterprise, isSelf, isOrg, isForked, listOptions())
+	default:
+		return "", fmt.Errorf("invalid --api %q, expected rest or graphql", apiBackend)
+	}
+}
+
+// Lists stars and/or gists, combining them into one list when both are asked for
+func fetchStarsAndGists(client piscator.HttpClient, sleeper piscator.Sleeper, observer piscator.Observer, name, token string, isSelf, isForked bool) (string, error) {
+	repos := []piscator.RepoModel{}
+	add := func(res string, err error) error {
+		if err != nil {
+			return err
+		}
+		var list []piscator.RepoModel
+		if err := json.Unmarshal([]byte(res), &list); err != nil {
+			return err
+		}
+		repos = append(repos, list...)
+		return nil
+	}
+
+	if isStarred {
+		if err := add(piscator.GetStarred(client, sleeper, observer, name, token, enterprise, isSelf, isForked)); err != nil {
+			return "", err
+		}
+	}
+	if isGists {
+		if err := add(piscator.GetGists(client, sleeper, observer, name, token, enterprise, isSelf)); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := json.MarshalIndent(repos, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Describes what a run listed and the flags narrowing it, for the metadata of
+// the repos file
+func repoFileMetadata(targets []piscator.Target) piscator.RepoFileMetadata {
+	var sources []string
+	if searchQuery != "" {
+		sources = append(sources, "search:"+searchQuery)
+	}
+	for _, target := range targets {
+		owner := target.Name
+		if isSelfBool {
+			owner = "self"
+		}
+		switch {
+		case searchQuery != "":
+		case isStarred || isGists:
+			if isStarred {
+				sources = append(sources, "starred:"+owner)
+			}
+			if isGists {
+				sources = append(sources, "gists:"+owner)
+			}
+		case isSelfBool:
+			sources = append(sources, "self")
+		case target.IsOrg:
+			sources = append(sources, "org:"+target.Name)
+		default:
+			sources = append(sources, "user:"+target.Name)
+		}
+	}
+
+	filters := map[string]string{}
+	for key, value := range map[string]string{
+		"language":    languageFilter,
+		"team":        listTeam,
+		"affiliation": listAffiliation,
+		"visibility":  listVisibility,
+		"type":        listType,
+		"host":        enterprise,
+	} {
+		if value != "" {
+			filters[key] = value
+		}
+	}
+	if isForkedBool {
+		filters["forked"] = "true"
+	}
+	if apiBackend != "rest" {
+		filters["api"] = apiBackend
+	}
+
+	return piscator.RepoFileMetadata{
+		Source:    strings.Join(sources, ","),
+		CreatedAt: time.Now().UTC(),
+		Version:   Version,
+		Filters:   filters,
+	}
+}
@@ -1,63 +1,221 @@
 package piscator
 
 import (
+	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/shimman-dev/piscator/pkg/piscator"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
-var isVerbose bool
+var isVerbose, isInteractive bool
+var repoInput string
 
-func reelRun(cmd *cobra.Command, args []string) {
-	if isSelfBool {
-		// Use the GitHub username associated with the token
-		name = "" // Set name to empty string for self
-	} else if len(args) < 1 && githubToken == "" && viper.GetString("github_token") == "" {
-		fmt.Println("Please provide a GitHub username or specify a token")
-		return
-	} else if len(args) >= 1 {
-		name = args[0]
+// Lets the user pick a subset of the listed repos from the terminal
+func pickRepos(res string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("--interactive requires a terminal")
 	}
 
-	if name == "" {
-		fmt.Println("Please provide a GitHub username or org name")
-		return
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, oldState)
+
+	return piscator.PickRepos(os.Stdin, os.Stdout, res)
+}
+
+// Reads the repos to reel from --input, a file or stdin, instead of listing
+// them. They're cloned into the directory given as argument, named after the
+// file otherwise.
+func readInput(args []string) ([]piscator.TargetRepos, error) {
+	dir := strings.TrimSuffix(filepath.Base(repoInput), filepath.Ext(repoInput))
+	var r io.Reader = os.Stdin
+	if repoInput == "-" {
+		dir = "repos"
+	} else {
+		f, err := os.Open(repoInput)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
 	}
 
-	tokenFileBool := viper.GetString("github_token") // get token from viper
+	switch {
+	case len(args) > 1:
+		return nil, fmt.Errorf("--input takes at most one name, the directory reel clones into")
+	case len(args) == 1:
+		dir = args[0]
+	}
+
+	repos, err := piscator.ReadRepoList(r)
+	if err != nil {
+		return nil, err
+	}
+	if languageFilter != "" {
+		data, err := json.Marshal(repos)
+		if err != nil {
+			return nil, err
+		}
+		res, err := piscator.RepoByLanguage(string(data), languageFilter)
+		if err != nil {
+			return nil, err
+		}
+		repos = nil
+		if err := json.Unmarshal([]byte(res), &repos); err != nil {
+			return nil, err
+		}
+	}
+	return []piscator.TargetRepos{{Target: piscator.Target{Name: dir}, Repos: repos}}, nil
+}
+
+func reelRun(cmd *cobra.Command, args []string) {
 	isForkedBool, _ = cmd.PersistentFlags().GetBool("forked")
 	makeFileBool, _ = cmd.PersistentFlags().GetBool("makeFile")
+	isVerbose, _ = cmd.PersistentFlags().GetBool("verbose")
 
-	sleeper := &piscator.RealSleeper{}
+	var results []piscator.TargetRepos
+	var targets []piscator.Target
+	var err error
+	if repoInput != "" {
+		if isStarred || isGists || searchQuery != "" || targetsFile != "" {
+			fmt.Println("Errors: --input can't be combined with other repo sources")
+			return
+		}
+		results, err = readInput(args)
+	} else {
+		targets, err = collectTargets(args, isOrgBool)
+		if err == nil && len(targets) == 0 {
+			fmt.Println("Please provide a GitHub username or org name")
+			return
+		}
+	}
+	if err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
 
-	res, err := piscator.GetRepos(http.DefaultClient, sleeper, name, tokenFileBool, username, password, enterprise, isSelfBool, isOrgBool, isForkedBool, makeFileBool)
+	// logs are written through the progress view so they don't tear through it
+	progress := piscator.NewProgress(os.Stdout)
+	logger, err := newLogger(progress, isVerbose)
+	if err != nil {
+		fmt.Printf("Errors: %s", err)
+		return
+	}
+	observer := piscator.Observers(progress, piscator.NewLogObserver(logger))
+	token, err := resolveToken(cmd, logger)
 	if err != nil {
 		fmt.Printf("Errors: %s", err)
 		return
 	}
 
-	if languageFilter != "" {
-		res, err = piscator.RepoByLanguage(res, languageFilter)
+	if repoInput == "" {
+		results, err = fetchTargets(observer, targets, token, isSelfBool, isForkedBool, makeFileBool)
 		if err != nil {
-			fmt.Printf("Error filtering repositories by language: %s", err)
+			fmt.Printf("Errors: %s", err)
 			return
 		}
 	}
 
-	concurrentLimit := int8(10)
-	isVerbose, _ = cmd.PersistentFlags().GetBool("verbose")
+	if isInteractive {
+		res, err := piscator.MergeTargetRepos(results)
+		if err == nil {
+			res, err = pickRepos(res)
+		}
+		if err == nil {
+			results, err = piscator.KeepTargetRepos(results, res)
+		}
+		if err != nil {
+			fmt.Printf("Errors: %s", err)
+			return
+		}
+	}
 
-	err = piscator.CloneReposFromJson(piscator.RealCommandExecutor{}, res, name, concurrentLimit, isVerbose)
+	// a single target keeps the terse output
+	if len(results) == 1 {
+		if _, err := reelTarget(observer, results[0], token); err != nil {
+			fmt.Printf("Errors: %s", err)
+			return
+		}
+		fmt.Println("success friend :)")
+		return
+	}
+
+	summaries := make([]piscator.TargetSummary, len(results))
+	for i, result := range results {
+		summaries[i] = piscator.TargetSummary{Name: result.Target.Name, Repos: len(result.Repos), Err: result.Err}
+		if result.Err != nil {
+			continue
+		}
+		summaries[i].Failed, summaries[i].Err = reelTarget(observer, result, token)
+		// failed clones are counted, the error is only kept when nothing ran
+		if summaries[i].Failed > 0 {
+			summaries[i].Err = nil
+		}
+	}
+	fmt.Println(piscator.FormatTargetSummary(summaries))
+}
 
+// Clones the repos of one target into a directory named after it, returning
+// how many failed
+func reelTarget(observer piscator.Observer, result piscator.TargetRepos, token string) (int, error) {
+	if result.Err != nil {
+		return 0, result.Err
+	}
+	res, err := json.Marshal(result.Repos)
 	if err != nil {
-		fmt.Printf("Errors: %s", err)
-		return
+		return 0, err
+	}
+
+	concurrentLimit := int8(10)
+
+	name := result.Target.Name
+	owner, err := installationOwner(result)
+	if err != nil {
+		return 0, err
+	}
+	executor, cleanup, err := gitExecutor(owner, token, piscator.RealCommandExecutor{Env: tlsConfig.GitEnv()})
+	if err != nil {
+		return 0, err
 	}
+	defer cleanup()
 
-	fmt.Println("success friend :)")
+	failed := 0
+	observer = piscator.Observers(observer, piscator.ObserverFunc(func(event piscator.Event) {
+		if finished, ok := event.(piscator.ReelFinished); ok {
+			failed = finished.Failed
+		}
+	}))
+	err = piscator.CloneReposFromJson(executor, observer, string(res), name, concurrentLimit)
+	return failed, err
+}
+
+// Returns the owner whose GitHub App installation clones a target's repos.
+// A target is named after the directory it's cloned into, so repos read from
+// --input are cloned with the installation of the owner in their URLs.
+func installationOwner(result piscator.TargetRepos) (string, error) {
+	if repoInput == "" || viper.GetInt64("app_id") == 0 {
+		return result.Target.Name, nil
+	}
+
+	owner := ""
+	for _, repo := range result.Repos {
+		repoOwner := piscator.RepoOwnerFromURL(repo.URL)
+		if owner != "" && repoOwner != owner && viper.GetInt64("app_installation_id") == 0 {
+			return "", fmt.Errorf("--input lists repos of %s and %s, pass --app-installation-id to clone them as a GitHub App", owner, repoOwner)
+		}
+		owner = repoOwner
+	}
+	return owner, nil
 }
 
 var reelCmd = &cobra.Command{
@@ -72,7 +230,7 @@ git repository. Like a seasoned fisherman, you'll nurture and cultivate these
 repositories, transforming them into valuable assets for your coding endeavors.
 Unleash your fishing prowess, reel in those repositories, and embark on a coding
 voyage like no other.`,
-	Args: cobra.MinimumNArgs(1),
+	Args: targetArgs,
 	Run:  reelRun,
 }
 
@@ -83,20 +241,25 @@ func init() {
 	reelCmd.PersistentFlags().BoolVarP(&isOrgBool, "org", "o", false, "Is an organization")
 	reelCmd.PersistentFlags().BoolVarP(&isForkedBool, "forked", "x", false, "Include forked repositories")
 	reelCmd.PersistentFlags().BoolVarP(&makeFileBool, "makeFile", "f", false, "Generate a repos.json file")
-	reelCmd.PersistentFlags().BoolVarP(&isVerbose, "verbose", "v", false, "logs detailed messaging to stdout")
+	reelCmd.PersistentFlags().BoolVarP(&isVerbose, "verbose", "v", false, "logs detailed messaging to stdout, same as --log-level debug")
+	reelCmd.PersistentFlags().BoolVarP(&isInteractive, "interactive", "i", false, "Pick which repos to reel from a fuzzy finder")
 
 	reelCmd.PersistentFlags().StringVarP(&languageFilter, "language", "l", "", "Filter repositories by language(s)")
+	addListFlags(reelCmd)
+	addSourceFlags(reelCmd)
+	addTargetFlags(reelCmd)
+	reelCmd.PersistentFlags().BoolVar(&isMetadata, "metadata", false, "Record the source, time, piscator version and filters in repos.json")
+	reelCmd.PersistentFlags().StringVar(&repoInput, "input", "", "Reel the repos in a file instead of listing them: cast output, git URLs or CSV, - for stdin")
+	reelCmd.PersistentFlags().StringVar(&apiBackend, "api", "rest", "GitHub API used to list repos: rest or graphql")
 
 	reelCmd.PersistentFlags().StringVarP(&githubToken, "token", "t", "", "GitHub personal access token")
 	reelCmd.PersistentFlags().StringVarP(&username, "username", "u", "", "GitHub username")
 	reelCmd.PersistentFlags().StringVarP(&password, "password", "p", "", "GitHub password")
+	reelCmd.PersistentFlags().StringVar(&enterprise, "host", "", "GitHub Enterprise Server host to list and clone from")
 	reelCmd.PersistentFlags().StringVarP(&enterprise, "enterprise", "e", "", "GitHub Enterprise URL")
+	reelCmd.PersistentFlags().MarkDeprecated("enterprise", "use --host instead")
 
-	// bind the token flags to env keys
-	viper.BindPFlag("github_token", castCmd.PersistentFlags().Lookup("token"))
-	viper.BindPFlag("username", castCmd.PersistentFlags().Lookup("username"))
-	viper.BindPFlag("password", castCmd.PersistentFlags().Lookup("password"))
-
+	// the token flags are bound to env keys by the running command, see resolveToken
 	viper.BindEnv("github_token", "GITHUB_TOKEN")
 	viper.BindEnv("username", "GITHUB_USERNAME")
 	viper.BindEnv("password", "GITHUB_PASSWORD")
@@ -2,12 +2,71 @@ package piscator
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/shimman-dev/piscator/pkg/piscator"
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 )
 
+var logFormat, logLevel string
+var isNoCache bool
+var cacheTTL time.Duration
+var tlsConfig piscator.TLSConfig
+
+// Version is the piscator release, set by main from the build flags
+var Version = "dev"
+
+// Returns the client that talks to the forge, using the --ca-bundle and
+// --client-cert given for GitHub Enterprise Server
+func transportClient() (*http.Client, error) {
+	return tlsConfig.Client()
+}
+
+// Returns the client used for GitHub API calls, caching responses on disk
+// unless --no-cache was given or there's no cache directory.
+func newHTTPClient() (piscator.HttpClient, error) {
+	client, err := transportClient()
+	if err != nil {
+		return nil, err
+	}
+	if isNoCache {
+		return client, nil
+	}
+	dir, err := piscator.DefaultCacheDir()
+	if err != nil {
+		return client, nil
+	}
+	return piscator.NewCachingClient(client, dir, cacheTTL), nil
+}
+
+// Builds the logger used for library output from the --log-format and
+// --log-level flags, verbose lowers the level to debug.
+func newLogger(w io.Writer, verbose bool) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(logLevel)); err != nil {
+		return nil, fmt.Errorf("invalid --log-level %q", logLevel)
+	}
+	if verbose && level > slog.LevelDebug {
+		level = slog.LevelDebug
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	switch logFormat {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid --log-format %q, expected text or json", logFormat)
+	}
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "piscator",
 	Short: "piscator is a CLT for cloning GitHub repos",
@@ -49,7 +108,19 @@ var generateManCmd = &cobra.Command{
 	},
 }
 
+// Splits a comma separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 func Execute() error {
+	rootCmd.Version = Version
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Whoops. There was an error while executing your piscator command '%s'", err)
 		os.Exit(1)
@@ -59,5 +130,14 @@ func Execute() error {
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log output format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().BoolVar(&isNoCache, "no-cache", false, "Don't cache GitHub API responses")
+	rootCmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", 0, "Serve cached API responses younger than this without revalidating")
+
+	rootCmd.PersistentFlags().StringVar(&tlsConfig.CAFile, "ca-bundle", "", "PEM bundle of CAs to trust for GitHub Enterprise Server")
+	rootCmd.PersistentFlags().StringVar(&tlsConfig.CertFile, "client-cert", "", "PEM client certificate for GitHub Enterprise Server")
+	rootCmd.PersistentFlags().StringVar(&tlsConfig.KeyFile, "client-key", "", "PEM key of the client certificate")
+
 	rootCmd.AddCommand(generateManCmd)
 }
@@ -0,0 +1,87 @@
+package piscator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/shimman-dev/piscator/pkg/piscator"
+	"github.com/spf13/cobra"
+)
+
+var isWorkReplace, isWorkVSCode, isWorkForce bool
+
+func workspaceRun(cmd *cobra.Command, args []string) error {
+	dir := args[0]
+
+	modules, err := piscator.FindGoModules(dir)
+	if err != nil {
+		return err
+	}
+	if len(modules) == 0 {
+		return fmt.Errorf("no go.mod found under %s", dir)
+	}
+
+	// a go.work may have been written by hand
+	goWork := filepath.Join(dir, "go.work")
+	if _, err := os.Stat(goWork); err == nil && !isWorkForce {
+		return fmt.Errorf("%s already exists, use --force to replace it", goWork)
+	}
+	err = piscator.WriteFileAtomic(goWork, func(w io.Writer) error {
+		_, err := io.WriteString(w, piscator.GoWork(modules, isWorkReplace))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %s using %d modules\n", goWork, len(modules))
+
+	if isWorkVSCode {
+		repos, err := piscator.FindRepos(dir)
+		if err != nil {
+			return err
+		}
+		data, err := piscator.CodeWorkspace(repos)
+		if err != nil {
+			return err
+		}
+
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		codeWorkspace := filepath.Join(dir, filepath.Base(abs)+".code-workspace")
+		err = piscator.WriteFileAtomic(codeWorkspace, func(w io.Writer) error {
+			_, err := w.Write(data)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("wrote %s with %d folders\n", codeWorkspace, len(repos))
+	}
+	return nil
+}
+
+var workspaceCmd = &cobra.Command{
+	Use:     "workspace dir",
+	Aliases: []string{"w"},
+	Short:   "write a go.work for the Go modules in reeled repos",
+	Long: `All hands on deck! A fleet sails best when lashed together. The workspace
+command searches every reeled repository for Go modules and binds them into a
+single go.work, so changes made in one hold are felt across the whole armada,
+and can chart a VS Code workspace with every repository as a folder.`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          workspaceRun,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	workspaceCmd.PersistentFlags().BoolVar(&isWorkReplace, "replace", false, "Replace the versions modules require of each other with the local copies")
+	workspaceCmd.PersistentFlags().BoolVar(&isWorkVSCode, "vscode", false, "Also write a VS Code .code-workspace listing every repo")
+	workspaceCmd.PersistentFlags().BoolVar(&isWorkForce, "force", false, "Replace an existing go.work")
+
+	rootCmd.AddCommand(workspaceCmd)
+}
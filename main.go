@@ -4,6 +4,10 @@ import (
 	"github.com/shimman-dev/piscator/cmd/piscator"
 )
 
+// set with -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
+	piscator.Version = version
 	piscator.Execute()
 }
@@ -0,0 +1,194 @@
+package piscator
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// installation tokens are refreshed this long before they expire so a clone
+// never starts with a token about to lapse
+const appTokenRefreshMargin = 5 * time.Minute
+
+// AppTokenSource is a TokenSource for a GitHub App installation. It signs a
+// JWT with the app's private key, exchanges it for an installation token and
+// refreshes that token before it expires.
+type AppTokenSource struct {
+	Client         HttpClient
+	AppID          int64
+	InstallationID int64
+	// Owner is the org or user whose installation is looked up when
+	// InstallationID isn't known
+	Owner   string
+	APIBase string
+
+	key *rsa.PrivateKey
+	now func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Creates an AppTokenSource from the app id and PEM encoded private key. When
+// installationID is zero the installation is discovered for owner.
+func NewAppTokenSource(client HttpClient, appID int64, keyPEM []byte, installationID int64, owner string) (*AppTokenSource, error) {
+	key, err := parseRSAPrivateKey(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if installationID == 0 && owner == "" {
+		return nil, errors.New("a GitHub App needs an installation id or an org/user to find it for")
+	}
+
+	return &AppTokenSource{
+		Client:         client,
+		AppID:          appID,
+		InstallationID: installationID,
+		Owner:          owner,
+		APIBase:        "https://api.github.com",
+		key:            key,
+		now:            time.Now,
+	}, nil
+}
+
+func parseRSAPrivateKey(keyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("GitHub App private key isn't PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GitHub App private key isn't an RSA key")
+	}
+	return key, nil
+}
+
+// Returns the installation token, exchanging a new one when the current token
+// is missing or about to expire.
+func (s *AppTokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && s.now().Add(appTokenRefreshMargin).Before(s.expires) {
+		return s.token, nil
+	}
+
+	jwt, err := s.jwt()
+	if err != nil {
+		return "", err
+	}
+
+	if s.InstallationID == 0 {
+		id, err := s.findInstallation(jwt)
+		if err != nil {
+			return "", err
+		}
+		s.InstallationID = id
+	}
+
+	var body struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", s.APIBase, s.InstallationID)
+	if err := s.appRequest("POST", url, jwt, &body); err != nil {
+		return "", fmt.Errorf("error creating installation token: %w", err)
+	}
+
+	s.token, s.expires = body.Token, body.ExpiresAt
+	return s.token, nil
+}
+
+// Looks up the app's installation on an org, falling back to a user account
+func (s *AppTokenSource) findInstallation(jwt string) (int64, error) {
+	var body struct {
+		ID int64 `json:"id"`
+	}
+
+	err := s.appRequest("GET", fmt.Sprintf("%s/orgs/%s/installation", s.APIBase, s.Owner), jwt, &body)
+	if errors.Is(err, ErrNotFound) {
+		err = s.appRequest("GET", fmt.Sprintf("%s/users/%s/installation", s.APIBase, s.Owner), jwt, &body)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error finding the GitHub App installation for %s: %w", s.Owner, err)
+	}
+	return body.ID, nil
+}
+
+func (s *AppTokenSource) appRequest(method, url, jwt string, v any) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "shimman-dev/piscator")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return &StatusError{URL: url, StatusCode: res.StatusCode, Err: ErrNotFound}
+	case res.StatusCode == http.StatusUnauthorized:
+		return &StatusError{URL: url, StatusCode: res.StatusCode, Err: ErrUnauthorized}
+	case res.StatusCode >= 300:
+		return &StatusError{URL: url, StatusCode: res.StatusCode}
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// Signs the short lived RS256 JWT GitHub expects from an app. iat is set a
+// minute in the past to allow for clock drift.
+func (s *AppTokenSource) jwt() (string, error) {
+	now := s.now()
+	header := `{"alg":"RS256","typ":"JWT"}`
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(s.AppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	enc := base64.RawURLEncoding
+	b.WriteString(enc.EncodeToString([]byte(header)))
+	b.WriteByte('.')
+	b.WriteString(enc.EncodeToString(claims))
+
+	digest := sha256.Sum256(b.Bytes())
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	b.WriteByte('.')
+	b.WriteString(enc.EncodeToString(sig))
+
+	return b.String(), nil
+}
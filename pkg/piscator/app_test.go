@@ -0,0 +1,110 @@
+package piscator
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestAppKey(t *testing.T) (*rsa.PrivateKey, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return key, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestAppTokenSource(t *testing.T) {
+	key, keyPEM := newTestAppKey(t)
+	now := time.Date(2023, 7, 1, 12, 0, 0, 0, time.UTC)
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 404, body: `{"message": "Not Found"}`},
+		{status: 200, body: `{"id": 42}`},
+		{status: 201, body: `{"token": "ghs_first", "expires_at": "2023-07-01T13:00:00Z"}`},
+		{status: 201, body: `{"token": "ghs_second", "expires_at": "2023-07-01T14:00:00Z"}`},
+	}}
+
+	source, err := NewAppTokenSource(client, 1234, keyPEM, 0, "shimman-dev")
+	if err != nil {
+		t.Fatalf("NewAppTokenSource() error = %v", err)
+	}
+	source.now = func() time.Time { return now }
+
+	token, err := source.Token()
+	if err != nil || token != "ghs_first" {
+		t.Fatalf("Expected ghs_first, got %q (%v)", token, err)
+	}
+
+	// the installation is looked up on the org and then the user
+	wantURLs := []string{
+		"https://api.github.com/orgs/shimman-dev/installation",
+		"https://api.github.com/users/shimman-dev/installation",
+		"https://api.github.com/app/installations/42/access_tokens",
+	}
+	for i, want := range wantURLs {
+		if got := client.requests[i].URL.String(); got != want {
+			t.Errorf("Expected request %d to %s, got %s", i, want, got)
+		}
+	}
+
+	// the JWT is signed by the app key and issued by the app
+	jwt := strings.TrimPrefix(client.requests[2].Header.Get("Authorization"), "Bearer ")
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a JWT, got %q", jwt)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("Expected a valid signature, got %v", err)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	json.Unmarshal(payload, &claims)
+	if claims.Iss != "1234" || claims.Iat != now.Add(-time.Minute).Unix() || claims.Exp != now.Add(9*time.Minute).Unix() {
+		t.Errorf("Unexpected claims %+v", claims)
+	}
+
+	// the token is reused until it's close to expiring
+	now = now.Add(30 * time.Minute)
+	if token, _ := source.Token(); token != "ghs_first" || len(client.requests) != 3 {
+		t.Errorf("Expected the cached token, got %q after %d requests", token, len(client.requests))
+	}
+	now = now.Add(26 * time.Minute)
+	if token, _ := source.Token(); token != "ghs_second" || len(client.requests) != 4 {
+		t.Errorf("Expected a refreshed token, got %q after %d requests", token, len(client.requests))
+	}
+}
+
+func TestAppTokenSourceErrors(t *testing.T) {
+	_, keyPEM := newTestAppKey(t)
+
+	if _, err := NewAppTokenSource(&SequenceHttpClient{}, 1, []byte("not a key"), 1, ""); err == nil {
+		t.Errorf("Expected an error for an invalid key")
+	}
+	if _, err := NewAppTokenSource(&SequenceHttpClient{}, 1, keyPEM, 0, ""); err == nil {
+		t.Errorf("Expected an error without an installation or owner")
+	}
+
+	client := &SequenceHttpClient{responses: []mockResponse{{status: 401, body: `{"message": "Bad credentials"}`}}}
+	source, err := NewAppTokenSource(client, 1, keyPEM, 42, "")
+	if err != nil {
+		t.Fatalf("NewAppTokenSource() error = %v", err)
+	}
+	if _, err := source.Token(); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected error %v, got %v", ErrUnauthorized, err)
+	}
+}
@@ -0,0 +1,40 @@
+package piscator
+
+import (
+	"net/http"
+)
+
+// TokenSource supplies the token used to authenticate with GitHub. Sources
+// with expiring tokens refresh them, so Token is called for every request.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// StaticToken is a TokenSource for a token that never changes, such as a
+// personal access token
+type StaticToken string
+
+func (t StaticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+// AuthClient is an HttpClient that authenticates every request with the
+// current token from Source.
+type AuthClient struct {
+	Client HttpClient
+	Source TokenSource
+}
+
+func (c *AuthClient) Do(req *http.Request) (*http.Response, error) {
+	token, err := c.Source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	return c.Client.Do(req)
+}
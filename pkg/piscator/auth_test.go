@@ -0,0 +1,48 @@
+package piscator
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+type sequenceTokenSource struct {
+	tokens []string
+	err    error
+}
+
+func (s *sequenceTokenSource) Token() (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	token := s.tokens[0]
+	if len(s.tokens) > 1 {
+		s.tokens = s.tokens[1:]
+	}
+	return token, nil
+}
+
+func TestAuthClient(t *testing.T) {
+	client := &SequenceHttpClient{responses: []mockResponse{{status: 200, body: "[]"}}}
+	auth := &AuthClient{Client: client, Source: &sequenceTokenSource{tokens: []string{"first", "second"}}}
+
+	for _, want := range []string{"Bearer first", "Bearer second"} {
+		req, _ := http.NewRequest("GET", "https://api.github.com/orgs/shimman-dev/repos", nil)
+		req.Header.Set("Authorization", "token stale")
+		if _, err := auth.Do(req); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		if req.Header.Get("Authorization") != "token stale" {
+			t.Errorf("Expected the caller's request to be left alone")
+		}
+		if got := client.requests[len(client.requests)-1].Header.Get("Authorization"); got != want {
+			t.Errorf("Expected %q, got %q", want, got)
+		}
+	}
+
+	failing := &AuthClient{Client: client, Source: &sequenceTokenSource{err: errors.New("no token")}}
+	req, _ := http.NewRequest("GET", "https://api.github.com/orgs/shimman-dev/repos", nil)
+	if _, err := failing.Do(req); err == nil {
+		t.Errorf("Expected an error but did not get one")
+	}
+}
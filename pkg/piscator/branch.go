@@ -0,0 +1,178 @@
+package piscator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// worktrees of a repo are checked out next to it in <repo>.worktrees/<branch>
+const worktreesSuffix = ".worktrees"
+
+// BranchOptions says what BranchRepos does in every repo
+type BranchOptions struct {
+	Branch string
+	// Create makes Branch from Base, otherwise an existing Branch is checked out
+	Create bool
+	// Base is the branch Branch is created from, each repo's HEAD when empty
+	Base string
+	// Worktree checks Branch out in <repo>.worktrees/<Branch> instead of
+	// switching the repo's own checkout
+	Worktree bool
+	// Push pushes Branch to Remote and sets it as the upstream
+	Push   bool
+	Remote string
+}
+
+// BranchResult is what BranchRepos did in one repo
+type BranchResult struct {
+	Repo string
+	// Path is where Branch is checked out
+	Path string
+	// Missing is the base or branch the repo lacked, nothing was done then
+	Missing string
+	Pushed  bool
+	Err     error
+}
+
+// Returns the path of a repo's worktree for branch
+func WorktreePath(repoPath, branch string) string {
+	return filepath.Join(repoPath+worktreesSuffix, filepath.FromSlash(branch))
+}
+
+// Creates or checks out a branch in every repo under dir concurrently, repos
+// being directories relative to dir such as FindRepos returns. Results are in
+// the order of repos.
+func BranchRepos(executor CommandExecutor, dir string, repos []string, opts BranchOptions, concurrentLimit int) []BranchResult {
+	if opts.Remote == "" {
+		opts.Remote = "origin"
+	}
+
+	results := make([]BranchResult, len(repos))
+	sem := make(chan struct{}, concurrentLimit)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(i int, repo string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = branchRepo(executor, filepath.Join(dir, filepath.FromSlash(repo)), opts)
+			results[i].Repo = repo
+		}(i, repo)
+	}
+	wg.Wait()
+	return results
+}
+
+func branchRepo(executor CommandExecutor, repoPath string, opts BranchOptions) BranchResult {
+	git := func(arg ...string) error {
+		out, err := executor.ExecuteCommandInDir(repoPath, "git", arg...)
+		if err != nil {
+			// git's last line says what went wrong, and keeps the table readable
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			return fmt.Errorf("git %s: %w: %s", arg[0], err, lines[len(lines)-1])
+		}
+		return nil
+	}
+	// a branch is known when it exists locally or on the remote
+	resolve := func(branch string) (string, bool) {
+		for _, ref := range []string{"refs/heads/" + branch, "refs/remotes/" + opts.Remote + "/" + branch} {
+			if _, err := executor.ExecuteCommandInDir(repoPath, "git", "rev-parse", "--verify", "--quiet", ref); err == nil {
+				return strings.TrimPrefix(strings.TrimPrefix(ref, "refs/heads/"), "refs/remotes/"), true
+			}
+		}
+		return "", false
+	}
+
+	result := BranchResult{Path: repoPath}
+	// git runs in the repo, so the worktree is given to it as an absolute path
+	worktree := ""
+	if opts.Worktree {
+		result.Path = WorktreePath(repoPath, opts.Branch)
+		abs, err := filepath.Abs(result.Path)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		worktree = abs
+	}
+
+	if opts.Create {
+		start := "HEAD"
+		if opts.Base != "" {
+			ref, ok := resolve(opts.Base)
+			if !ok {
+				result.Missing = opts.Base
+				return result
+			}
+			start = ref
+		}
+		// new branches don't track the base they start from
+		if opts.Worktree {
+			result.Err = git("worktree", "add", "--no-track", "-b", opts.Branch, worktree, start)
+		} else {
+			result.Err = git("checkout", "--no-track", "-b", opts.Branch, start)
+		}
+	} else {
+		if _, ok := resolve(opts.Branch); !ok {
+			result.Missing = opts.Branch
+			return result
+		}
+		// git creates a local branch tracking the remote one when needed
+		if opts.Worktree {
+			result.Err = git("worktree", "add", worktree, opts.Branch)
+		} else {
+			result.Err = git("checkout", opts.Branch)
+		}
+	}
+
+	if result.Err == nil && opts.Push {
+		result.Err = git("push", "--set-upstream", opts.Remote, opts.Branch)
+		result.Pushed = result.Err == nil
+	}
+	return result
+}
+
+// Formats branch results as a table, followed by the repos that lacked the
+// base or branch
+func FormatBranchResults(results []BranchResult, opts BranchOptions) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REPO\tRESULT\tPATH")
+
+	action := "checked out"
+	if opts.Create {
+		action = "created"
+	}
+	if opts.Push {
+		action += ", pushed"
+	}
+
+	missing := map[string][]string{}
+	var failed int
+	for _, result := range results {
+		switch {
+		case result.Missing != "":
+			missing[result.Missing] = append(missing[result.Missing], result.Repo)
+			fmt.Fprintf(w, "%s\tno %s\n", result.Repo, result.Missing)
+		case result.Err != nil:
+			failed++
+			fmt.Fprintf(w, "%s\tfailed: %s\t%s\n", result.Repo, result.Err, result.Path)
+		default:
+			fmt.Fprintf(w, "%s\t%s\t%s\n", result.Repo, action, result.Path)
+		}
+	}
+	w.Flush()
+
+	for _, name := range []string{opts.Base, opts.Branch} {
+		if repos := missing[name]; name != "" && len(repos) > 0 {
+			fmt.Fprintf(&b, "%d repos lack %s: %s\n", len(repos), name, strings.Join(repos, ", "))
+		}
+	}
+	fmt.Fprintf(&b, "%d repos, %d failed\n", len(results), failed)
+	return b.String()
+}
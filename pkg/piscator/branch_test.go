@@ -0,0 +1,171 @@
+package piscator
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RefExecutor records the git commands run in each repo, refs lists the refs
+// every repo has and fail the commands that fail
+type RefExecutor struct {
+	MockCommandExecutor
+	refs map[string][]string
+	fail map[string]bool
+
+	mu       sync.Mutex
+	commands map[string][]string
+}
+
+func (m *RefExecutor) ExecuteCommandInDir(dir, name string, arg ...string) ([]byte, error) {
+	repo := filepath.Base(dir)
+	command := strings.Join(arg, " ")
+	if arg[0] == "rev-parse" {
+		for _, ref := range m.refs[repo] {
+			if ref == arg[len(arg)-1] {
+				return nil, nil
+			}
+		}
+		return nil, errors.New("exit status 1")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.commands == nil {
+		m.commands = map[string][]string{}
+	}
+	m.commands[repo] = append(m.commands[repo], command)
+	if m.fail[repo+" "+arg[0]] {
+		return []byte("Preparing worktree\nfatal: it went wrong\n"), errors.New("exit status 128")
+	}
+	return []byte("ok"), nil
+}
+
+func TestBranchRepos(t *testing.T) {
+	dir := t.TempDir()
+	worktree := func(repo, branch string) string {
+		abs, _ := filepath.Abs(WorktreePath(filepath.Join(dir, repo), branch))
+		return abs
+	}
+	refs := map[string][]string{
+		"api": {"refs/heads/main", "refs/heads/dev"},
+		"lib": {"refs/heads/main", "refs/remotes/origin/dev"},
+		"web": {"refs/heads/main"},
+	}
+
+	tests := []struct {
+		name     string
+		opts     BranchOptions
+		fail     map[string]bool
+		commands map[string][]string
+		missing  []string
+		failed   []string
+	}{
+		{
+			name: "create from HEAD",
+			opts: BranchOptions{Branch: "feat", Create: true},
+			commands: map[string][]string{
+				"api": {"checkout --no-track -b feat HEAD"},
+				"lib": {"checkout --no-track -b feat HEAD"},
+				"web": {"checkout --no-track -b feat HEAD"},
+			},
+		},
+		{
+			name: "create from a base some repos lack",
+			opts: BranchOptions{Branch: "feat", Create: true, Base: "dev", Push: true},
+			commands: map[string][]string{
+				"api": {"checkout --no-track -b feat dev", "push --set-upstream origin feat"},
+				"lib": {"checkout --no-track -b feat origin/dev", "push --set-upstream origin feat"},
+			},
+			missing: []string{"web"},
+		},
+		{
+			name: "create worktrees",
+			opts: BranchOptions{Branch: "feat", Create: true, Base: "main", Worktree: true},
+			commands: map[string][]string{
+				"api": {"worktree add --no-track -b feat " + worktree("api", "feat") + " main"},
+				"lib": {"worktree add --no-track -b feat " + worktree("lib", "feat") + " main"},
+				"web": {"worktree add --no-track -b feat " + worktree("web", "feat") + " main"},
+			},
+		},
+		{
+			name: "check out a branch",
+			opts: BranchOptions{Branch: "dev"},
+			commands: map[string][]string{
+				"api": {"checkout dev"},
+				"lib": {"checkout dev"},
+			},
+			missing: []string{"web"},
+		},
+		{
+			name: "check out worktrees from another remote, failures aren't pushed",
+			opts: BranchOptions{Branch: "dev", Worktree: true, Push: true, Remote: "upstream"},
+			fail: map[string]bool{"api worktree": true},
+			commands: map[string][]string{
+				"api": {"worktree add " + worktree("api", "dev") + " dev"},
+			},
+			missing: []string{"lib", "web"},
+			failed:  []string{"api"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			executor := &RefExecutor{refs: refs, fail: test.fail}
+			results := BranchRepos(executor, dir, []string{"api", "lib", "web"}, test.opts, 2)
+
+			if !reflect.DeepEqual(executor.commands, test.commands) {
+				t.Errorf("Expected commands %v, got %v", test.commands, executor.commands)
+			}
+			var missing, failed []string
+			for i, result := range results {
+				if result.Repo != []string{"api", "lib", "web"}[i] {
+					t.Errorf("Expected results in the order of repos, got %s at %d", result.Repo, i)
+				}
+				if result.Missing != "" {
+					missing = append(missing, result.Repo)
+				}
+				if result.Err != nil {
+					failed = append(failed, result.Repo)
+					if !strings.HasSuffix(result.Err.Error(), ": fatal: it went wrong") {
+						t.Errorf("Expected the last line of git's output in %q", result.Err)
+					}
+				}
+				if result.Pushed != (test.opts.Push && result.Err == nil && result.Missing == "") {
+					t.Errorf("Expected %s pushed to be %v", result.Repo, !result.Pushed)
+				}
+			}
+			if !reflect.DeepEqual(missing, test.missing) {
+				t.Errorf("Expected missing %v, got %v", test.missing, missing)
+			}
+			if !reflect.DeepEqual(failed, test.failed) {
+				t.Errorf("Expected failed %v, got %v", test.failed, failed)
+			}
+		})
+	}
+}
+
+func TestFormatBranchResults(t *testing.T) {
+	opts := BranchOptions{Branch: "feat", Create: true, Base: "dev", Push: true}
+	results := []BranchResult{
+		{Repo: "api", Path: "fleet/api", Pushed: true},
+		{Repo: "lib", Path: "fleet/lib", Err: errors.New("git push: exit status 1: denied")},
+		{Repo: "web", Path: "fleet/web", Missing: "dev"},
+		{Repo: "cli", Path: "fleet/cli", Missing: "dev"},
+	}
+
+	want := `REPO  RESULT                                   PATH
+api   created, pushed                          fleet/api
+lib   failed: git push: exit status 1: denied  fleet/lib
+web   no dev
+cli   no dev
+2 repos lack dev: web, cli
+4 repos, 1 failed
+`
+	if got := FormatBranchResults(results, opts); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,208 @@
+package piscator
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheHeader is set on responses served by a CachingClient, to "hit" when
+// served without a request or "revalidated" when GitHub answered 304
+const CacheHeader = "X-Piscator-Cache"
+
+// CachingClient is an HttpClient that keeps GET responses on disk and
+// revalidates them with If-None-Match/If-Modified-Since. GitHub doesn't count
+// a 304 against the rate limit, so unchanged listings cost nothing.
+type CachingClient struct {
+	Client HttpClient
+	Dir    string
+	// responses younger than TTL are served without revalidating
+	TTL time.Duration
+
+	now func() time.Time
+}
+
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"last_modified,omitempty"`
+	StoredAt     time.Time   `json:"stored_at"`
+	Header       http.Header `json:"header"`
+}
+
+// Returns the cache directory under the user's cache home, e.g.
+// $XDG_CACHE_HOME/piscator/http.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "piscator", "http"), nil
+}
+
+// Creates a CachingClient around client storing responses in dir.
+func NewCachingClient(client HttpClient, dir string, ttl time.Duration) *CachingClient {
+	return &CachingClient{Client: client, Dir: dir, TTL: ttl, now: time.Now}
+}
+
+// Removes every cached response in dir.
+func ClearCache(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+func (c *CachingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return c.Client.Do(req)
+	}
+
+	key := cacheKey(req)
+	entry, body, ok := c.load(key)
+	if ok && c.TTL > 0 && c.now().Sub(entry.StoredAt) < c.TTL {
+		return cachedResponse(req, entry, body, "hit"), nil
+	}
+
+	if ok {
+		req = req.Clone(req.Context())
+		if entry.ETag != "" {
+			req.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			req.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	res, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && ok:
+		res.Body.Close()
+		entry.StoredAt = c.now()
+		c.store(key, entry, body)
+		cached := cachedResponse(req, entry, body, "revalidated")
+		// keep the fresh rate limit headers so budgets stay accurate
+		for _, h := range []string{"X-Ratelimit-Remaining", "X-Ratelimit-Reset"} {
+			if v := res.Header.Get(h); v != "" {
+				cached.Header.Set(h, v)
+			}
+		}
+		return cached, nil
+	case res.StatusCode == http.StatusOK && (res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != "" || c.TTL > 0):
+		fresh, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		c.store(key, cacheEntry{
+			URL:          redactURL(req),
+			ETag:         res.Header.Get("ETag"),
+			LastModified: res.Header.Get("Last-Modified"),
+			StoredAt:     c.now(),
+			Header:       res.Header,
+		}, fresh)
+		res.Body = io.NopCloser(bytes.NewReader(fresh))
+		return res, nil
+	default:
+		return res, nil
+	}
+}
+
+// Responses depend on who is asking, so the credentials are part of the key
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, req.URL.String())
+	io.WriteString(h, "\n")
+	io.WriteString(h, req.Header.Get("Authorization"))
+	io.WriteString(h, "\n")
+	io.WriteString(h, req.Header.Get("Accept"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// the URL without any basic auth credentials, for the entry's metadata
+func redactURL(req *http.Request) string {
+	u := *req.URL
+	u.User = nil
+	return u.String()
+}
+
+func cachedResponse(req *http.Request, entry cacheEntry, body []byte, status string) *http.Response {
+	header := entry.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(CacheHeader, status)
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func (c *CachingClient) load(key string) (cacheEntry, []byte, bool) {
+	var entry cacheEntry
+	meta, err := os.ReadFile(filepath.Join(c.Dir, key+".json"))
+	if err != nil {
+		return entry, nil, false
+	}
+	if err := json.Unmarshal(meta, &entry); err != nil {
+		return entry, nil, false
+	}
+	body, err := os.ReadFile(filepath.Join(c.Dir, key+".body"))
+	if err != nil {
+		return entry, nil, false
+	}
+	return entry, body, true
+}
+
+// The cache is best effort, failing to store a response isn't an error
+func (c *CachingClient) store(key string, entry cacheEntry, body []byte) {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return
+	}
+	meta, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// body first, a stale body next to fresh metadata would be served as current
+	if writeFileAtomic(filepath.Join(c.Dir, key+".body"), body, 0600) != nil {
+		return
+	}
+	writeFileAtomic(filepath.Join(c.Dir, key+".json"), meta, 0600)
+}
+
+// Writes data to a temporary file next to name and renames it into place, so
+// readers never see a partially written file.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
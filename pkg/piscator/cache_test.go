@@ -0,0 +1,110 @@
+package piscator
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestCachingClient(t *testing.T) {
+	dir := t.TempDir()
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, body: `[{"name": "repo1"}]`, headers: map[string]string{"ETag": `"abc"`}},
+		{status: 304, headers: map[string]string{"X-RateLimit-Remaining": "4999"}},
+		{status: 200, body: `[{"name": "repo2"}]`, headers: map[string]string{"ETag": `"def"`}},
+	}}
+	cache := NewCachingClient(client, dir, 0)
+
+	get := func() (*http.Response, string) {
+		req, _ := http.NewRequest("GET", "https://api.github.com/users/shimman-dev/repos", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		res, err := cache.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	// first request is stored
+	res, body := get()
+	if body != `[{"name": "repo1"}]` || res.Header.Get(CacheHeader) != "" {
+		t.Errorf("Expected a fresh response, got %q (%s)", body, res.Header.Get(CacheHeader))
+	}
+
+	// second is revalidated with the etag and served from the cache on a 304
+	res, body = get()
+	if got := client.requests[1].Header.Get("If-None-Match"); got != `"abc"` {
+		t.Errorf("Expected If-None-Match to be sent, got %q", got)
+	}
+	if res.StatusCode != 200 || body != `[{"name": "repo1"}]` || res.Header.Get(CacheHeader) != "revalidated" {
+		t.Errorf("Expected the cached body, got %d %q (%s)", res.StatusCode, body, res.Header.Get(CacheHeader))
+	}
+	if res.Header.Get("X-RateLimit-Remaining") != "4999" {
+		t.Errorf("Expected fresh rate limit headers, got %q", res.Header.Get("X-RateLimit-Remaining"))
+	}
+
+	// third sees a change and replaces the entry
+	_, body = get()
+	if body != `[{"name": "repo2"}]` {
+		t.Errorf("Expected the changed body, got %q", body)
+	}
+
+	// other credentials don't share entries
+	req, _ := http.NewRequest("GET", "https://api.github.com/users/shimman-dev/repos", nil)
+	res, err := cache.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	res.Body.Close()
+	if got := client.requests[3].Header.Get("If-None-Match"); got != "" {
+		t.Errorf("Expected no conditional request for other credentials, got %q", got)
+	}
+
+	if err := ClearCache(dir); err != nil {
+		t.Fatalf("ClearCache() error = %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected the cache directory to be removed")
+	}
+}
+
+func TestCachingClientTTL(t *testing.T) {
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, body: "[]", headers: map[string]string{"ETag": `"abc"`}},
+		{status: 304},
+	}}
+	now := time.Now()
+	cache := NewCachingClient(client, t.TempDir(), time.Hour)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "https://api.github.com/users/shimman-dev/repos", nil)
+		res, err := cache.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		res.Body.Close()
+		if i > 0 && res.Header.Get(CacheHeader) != "hit" {
+			t.Errorf("Expected a cache hit, got %q", res.Header.Get(CacheHeader))
+		}
+	}
+	if len(client.requests) != 1 {
+		t.Errorf("Expected fresh entries to skip the network, got %d requests", len(client.requests))
+	}
+
+	// once stale the entry is revalidated
+	now = now.Add(2 * time.Hour)
+	req, _ := http.NewRequest("GET", "https://api.github.com/users/shimman-dev/repos", nil)
+	res, err := cache.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	res.Body.Close()
+	if len(client.requests) != 2 || res.Header.Get(CacheHeader) != "revalidated" {
+		t.Errorf("Expected a revalidation, got %d requests (%s)", len(client.requests), res.Header.Get(CacheHeader))
+	}
+}
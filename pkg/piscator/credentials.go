@@ -0,0 +1,437 @@
+package piscator
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoCredentials is returned when a source has nothing stored for a host
+var ErrNoCredentials = errors.New("no credentials found")
+
+// Credential is a token found for a forge host and where it came from
+type Credential struct {
+	Username string
+	Token    string
+	Source   string
+}
+
+// CredentialProvider looks up the credential for a forge host such as
+// github.com or a GitHub Enterprise host.
+type CredentialProvider interface {
+	Credential(host string) (Credential, error)
+}
+
+// CredentialChain asks each provider in turn and returns the first credential
+// found. Errors from providers are only returned when none has a credential.
+type CredentialChain []CredentialProvider
+
+func (c CredentialChain) Credential(host string) (Credential, error) {
+	errs := []error{ErrNoCredentials}
+	for _, provider := range c {
+		cred, err := provider.Credential(host)
+		if err == nil {
+			return cred, nil
+		}
+		if !errors.Is(err, ErrNoCredentials) {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == 1 {
+		return Credential{}, ErrNoCredentials
+	}
+	return Credential{}, errors.Join(errs...)
+}
+
+// GhHosts reads the tokens the gh CLI stores in its hosts.yml
+type GhHosts struct {
+	Path string
+}
+
+// Returns where gh keeps hosts.yml, following GH_CONFIG_DIR and XDG_CONFIG_HOME
+// the same way gh does
+func DefaultGhHostsPath() string {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return filepath.Join(dir, "hosts.yml")
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh", "hosts.yml")
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI", "hosts.yml")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gh", "hosts.yml")
+}
+
+func (g GhHosts) Credential(host string) (Credential, error) {
+	data, err := os.ReadFile(g.Path)
+	if os.IsNotExist(err) {
+		return Credential{}, ErrNoCredentials
+	}
+	if err != nil {
+		return Credential{}, err
+	}
+
+	var hosts map[string]struct {
+		User       string `yaml:"user"`
+		OAuthToken string `yaml:"oauth_token"`
+	}
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return Credential{}, fmt.Errorf("error parsing %s: %w", g.Path, err)
+	}
+
+	// newer gh versions keep the token in the system keyring instead
+	entry, ok := hosts[host]
+	if !ok || entry.OAuthToken == "" {
+		return Credential{}, ErrNoCredentials
+	}
+	return Credential{Username: entry.User, Token: entry.OAuthToken, Source: g.Path}, nil
+}
+
+// Netrc reads credentials from a .netrc file, the password of a machine entry
+// is used as the token
+type Netrc struct {
+	Path string
+}
+
+// Returns the .netrc in the home directory, or the file NETRC points at
+func DefaultNetrcPath() string {
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".netrc")
+}
+
+func (n Netrc) Credential(host string) (Credential, error) {
+	f, err := os.Open(n.Path)
+	if os.IsNotExist(err) {
+		return Credential{}, ErrNoCredentials
+	}
+	if err != nil {
+		return Credential{}, err
+	}
+	defer f.Close()
+
+	machines, err := parseNetrc(f)
+	if err != nil {
+		return Credential{}, fmt.Errorf("error parsing %s: %w", n.Path, err)
+	}
+
+	// the API host is listed as often as the forge itself. The default entry
+	// is a catch-all for other hosts and never sent to the forge.
+	for _, name := range []string{host, "api." + host} {
+		if m, ok := machines[name]; ok && m.Password != "" {
+			return Credential{Username: m.Login, Token: m.Password, Source: n.Path}, nil
+		}
+	}
+	return Credential{}, ErrNoCredentials
+}
+
+type netrcEntry struct {
+	Login, Password string
+}
+
+// Parses netrc entries keyed by machine name, the default entry is keyed by
+// an empty name. macdef bodies are skipped.
+func parseNetrc(r io.Reader) (map[string]netrcEntry, error) {
+	machines := map[string]netrcEntry{}
+	scanner := bufio.NewScanner(r)
+
+	var current *netrcEntry
+	var name string
+	save := func() {
+		if current != nil {
+			if _, seen := machines[name]; !seen {
+				machines[name] = *current
+			}
+		}
+	}
+
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+
+		fields := strings.Fields(line)
+		for i := 0; i < len(fields); i++ {
+			switch fields[i] {
+			case "machine", "default":
+				save()
+				current, name = &netrcEntry{}, ""
+				if fields[i] == "machine" {
+					if i+1 >= len(fields) {
+						return nil, errors.New("machine without a name")
+					}
+					i++
+					name = fields[i]
+				}
+			case "login", "password", "account":
+				if current == nil || i+1 >= len(fields) {
+					return nil, fmt.Errorf("unexpected %q", fields[i])
+				}
+				i++
+				if fields[i-1] == "login" {
+					current.Login = fields[i]
+				} else if fields[i-1] == "password" {
+					current.Password = fields[i]
+				}
+			case "macdef":
+				inMacro = true
+				i = len(fields)
+			default:
+				if strings.HasPrefix(fields[i], "#") {
+					i = len(fields)
+				}
+			}
+		}
+	}
+	save()
+
+	return machines, scanner.Err()
+}
+
+// CredentialStore keeps tokens piscator was given with `piscator auth login`,
+// one per forge host.
+type CredentialStore interface {
+	Get(host string) (string, error)
+	Set(host, token string) error
+	Delete(host string) error
+}
+
+// StoredCredentials is a CredentialProvider backed by a CredentialStore
+type StoredCredentials struct {
+	Store CredentialStore
+}
+
+func (s StoredCredentials) Credential(host string) (Credential, error) {
+	token, err := s.Store.Get(host)
+	if err != nil {
+		return Credential{}, err
+	}
+	return Credential{Token: token, Source: "piscator auth login"}, nil
+}
+
+// Returns the Secret Service keyring when one is reachable, and otherwise an
+// encrypted file in the user's config directory that still needs a
+// passphrase before it can be used.
+func DefaultCredentialStore() (CredentialStore, error) {
+	if _, err := exec.LookPath("secret-tool"); err == nil && os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return &SecretServiceStore{}, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStore(filepath.Join(dir, "piscator", "credentials.enc"), ""), nil
+}
+
+// SecretServiceStore keeps tokens in the Secret Service keyring (GNOME
+// Keyring, KWallet) through libsecret's secret-tool.
+type SecretServiceStore struct {
+	// runs secret-tool, replaced in tests
+	run func(stdin string, arg ...string) ([]byte, error)
+}
+
+func (s *SecretServiceStore) secretTool(stdin string, arg ...string) ([]byte, error) {
+	if s.run != nil {
+		return s.run(stdin, arg...)
+	}
+	cmd := exec.Command("secret-tool", arg...)
+	// the token goes through stdin so it's never visible in the process list
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitErr.Stderr = stderr.Bytes()
+	}
+	if err != nil && stderr.Len() > 0 {
+		err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, err
+}
+
+func (s *SecretServiceStore) Get(host string) (string, error) {
+	out, err := s.secretTool("", "lookup", "service", "piscator", "host", host)
+	if token := strings.TrimSpace(string(out)); token != "" {
+		return token, nil
+	}
+	if err == nil {
+		return "", ErrNoCredentials
+	}
+	// secret-tool exits 1 without output when nothing is stored, a locked
+	// keyring or D-Bus failure says what went wrong on stderr
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(bytes.TrimSpace(exitErr.Stderr)) == 0 {
+		return "", ErrNoCredentials
+	}
+	return "", fmt.Errorf("error reading the keyring: %w", err)
+}
+
+func (s *SecretServiceStore) Set(host, token string) error {
+	_, err := s.secretTool(token, "store", "--label", "piscator: "+host, "service", "piscator", "host", host)
+	return err
+}
+
+func (s *SecretServiceStore) Delete(host string) error {
+	// secret-tool clear succeeds whether anything was stored or not
+	if _, err := s.Get(host); err != nil {
+		return err
+	}
+	_, err := s.secretTool("", "clear", "service", "piscator", "host", host)
+	return err
+}
+
+// FileStore keeps tokens in a file encrypted with AES-GCM, for systems
+// without a keyring. The key is derived from Passphrase.
+type FileStore struct {
+	Path       string
+	Passphrase string
+
+	iterations int
+}
+
+// ErrPassphraseRequired is returned by a FileStore holding tokens when it was
+// given no passphrase to decrypt them
+var ErrPassphraseRequired = errors.New("a passphrase is required to read the stored credentials")
+
+// PBKDF2 iterations recommended for HMAC-SHA256
+const fileStoreIterations = 600_000
+
+func NewFileStore(path, passphrase string) *FileStore {
+	return &FileStore{Path: path, Passphrase: passphrase, iterations: fileStoreIterations}
+}
+
+type encryptedFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+func (f *FileStore) Get(host string) (string, error) {
+	tokens, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	token, ok := tokens[host]
+	if !ok {
+		return "", ErrNoCredentials
+	}
+	return token, nil
+}
+
+func (f *FileStore) Set(host, token string) error {
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+	tokens[host] = token
+	return f.save(tokens)
+}
+
+func (f *FileStore) Delete(host string) error {
+	tokens, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := tokens[host]; !ok {
+		return ErrNoCredentials
+	}
+	delete(tokens, host)
+	return f.save(tokens)
+}
+
+// Returns the key for the passphrase and salt
+func (f *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if f.Passphrase == "" {
+		return nil, errors.New("the credential file needs a passphrase")
+	}
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(f.Passphrase), salt, f.iterations, 32, sha256.New))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (f *FileStore) load() (map[string]string, error) {
+	tokens := map[string]string{}
+	data, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", f.Path, err)
+	}
+	aead, err := f.cipher(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, errors.New("error decrypting credentials, wrong passphrase?")
+	}
+	err = json.Unmarshal(plain, &tokens)
+	return tokens, err
+}
+
+func (f *FileStore) save(tokens map[string]string) error {
+	plain, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	// a fresh salt and nonce every time the file is written
+	file := encryptedFile{Salt: make([]byte, 16)}
+	if _, err := rand.Read(file.Salt); err != nil {
+		return err
+	}
+	aead, err := f.cipher(file.Salt)
+	if err != nil {
+		return err
+	}
+	file.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(file.Nonce); err != nil {
+		return err
+	}
+	file.Data = aead.Seal(nil, file.Nonce, plain, nil)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+	return writeFileAtomic(f.Path, data, 0600)
+}
@@ -0,0 +1,230 @@
+package piscator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestGhHosts(t *testing.T) {
+	path := writeTestFile(t, "hosts.yml", `github.com:
+    users:
+        octocat:
+            oauth_token: gho_public
+    git_protocol: https
+    oauth_token: gho_public
+    user: octocat
+ghe.example.com:
+    user: octocat
+`)
+
+	tests := []struct {
+		host     string
+		expected Credential
+		wantErr  error
+	}{
+		{"github.com", Credential{Username: "octocat", Token: "gho_public", Source: path}, nil},
+		{"ghe.example.com", Credential{}, ErrNoCredentials},
+		{"gitlab.com", Credential{}, ErrNoCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			cred, err := GhHosts{Path: path}.Credential(tt.host)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if cred != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, cred)
+			}
+		})
+	}
+
+	if _, err := (GhHosts{Path: filepath.Join(t.TempDir(), "missing.yml")}).Credential("github.com"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected a missing file to have no credentials, got %v", err)
+	}
+}
+
+func TestNetrc(t *testing.T) {
+	path := writeTestFile(t, ".netrc", `# tokens for the forges
+machine api.github.com login octocat password ghp_api
+machine ghe.example.com
+	login admin
+	password ghp_enterprise
+macdef init
+	cd /pub
+	machine evil.example.com login x password y
+
+default login anonymous password guest
+`)
+
+	tests := []struct {
+		host     string
+		expected Credential
+	}{
+		{"github.com", Credential{Username: "octocat", Token: "ghp_api", Source: path}},
+		{"ghe.example.com", Credential{Username: "admin", Token: "ghp_enterprise", Source: path}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			cred, err := Netrc{Path: path}.Credential(tt.host)
+			if err != nil {
+				t.Fatalf("Credential() error = %v", err)
+			}
+			if cred != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, cred)
+			}
+		})
+	}
+
+	// the default entry and machines inside macros are never used
+	if _, err := (Netrc{Path: path}).Credential("evil.example.com"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected no credentials for an unlisted host, got %v", err)
+	}
+
+	broken := writeTestFile(t, ".netrc", "login octocat")
+	if _, err := (Netrc{Path: broken}).Credential("github.com"); err == nil || errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected a parse error, got %v", err)
+	}
+}
+
+func TestCredentialChain(t *testing.T) {
+	netrc := writeTestFile(t, ".netrc", "machine github.com login octocat password ghp_netrc")
+	brokenGh := writeTestFile(t, "hosts.yml", "github.com: [")
+	chain := CredentialChain{GhHosts{Path: brokenGh}, Netrc{Path: netrc}}
+
+	// a broken source doesn't hide the ones after it
+	cred, err := chain.Credential("github.com")
+	if err != nil || cred.Token != "ghp_netrc" {
+		t.Errorf("Expected the netrc token, got %+v (%v)", cred, err)
+	}
+
+	// nothing found reports both the miss and the broken source
+	_, err = chain.Credential("ghe.example.com")
+	if !errors.Is(err, ErrNoCredentials) || !strings.Contains(err.Error(), "hosts.yml") {
+		t.Errorf("Expected no credentials and the parse error, got %v", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "piscator", "credentials.enc")
+	store := NewFileStore(path, "correct horse")
+	store.iterations = 10
+
+	if _, err := store.Get("github.com"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected no credentials in a new store, got %v", err)
+	}
+	if err := store.Set("github.com", "gho_secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := store.Set("ghe.example.com", "gho_enterprise"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "gho_secret") {
+		t.Errorf("Expected the token to be encrypted, got %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file to be private, got %v", info.Mode().Perm())
+	}
+
+	if token, err := store.Get("github.com"); err != nil || token != "gho_secret" {
+		t.Errorf("Expected gho_secret, got %q (%v)", token, err)
+	}
+
+	wrong := NewFileStore(path, "wrong")
+	wrong.iterations = 10
+	if _, err := wrong.Get("github.com"); err == nil {
+		t.Errorf("Expected a wrong passphrase to fail")
+	}
+	if _, err := NewFileStore(path, "").Get("github.com"); !errors.Is(err, ErrPassphraseRequired) {
+		t.Errorf("Expected a missing passphrase to fail, got %v", err)
+	}
+
+	if err := store.Delete("github.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("github.com"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected the token to be deleted, got %v", err)
+	}
+	if token, _ := store.Get("ghe.example.com"); token != "gho_enterprise" {
+		t.Errorf("Expected other hosts to be kept, got %q", token)
+	}
+}
+
+// Returns the error of a command exiting with code, with stderr attached the
+// way secretTool attaches it
+func exitError(t *testing.T, code int, stderr string) error {
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Fatalf("Expected an exit error, got %v", err)
+	}
+	exitErr.Stderr = []byte(stderr)
+	return exitErr
+}
+
+func TestSecretServiceStore(t *testing.T) {
+	var calls []string
+	secrets := map[string]string{}
+	store := &SecretServiceStore{run: func(stdin string, arg ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(arg, " "))
+		host := arg[len(arg)-1]
+		switch arg[0] {
+		case "store":
+			secrets[host] = stdin
+		case "lookup":
+			if token, ok := secrets[host]; ok {
+				return []byte(token), nil
+			}
+			return nil, exitError(t, 1, "")
+		case "clear":
+			delete(secrets, host)
+		}
+		return nil, nil
+	}}
+
+	if _, err := store.Get("github.com"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected no credentials, got %v", err)
+	}
+	if err := store.Set("github.com", "gho_secret"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if token, err := store.Get("github.com"); err != nil || token != "gho_secret" {
+		t.Errorf("Expected gho_secret, got %q (%v)", token, err)
+	}
+	if err := store.Delete("github.com"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := store.Delete("github.com"); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected deleting nothing to fail, got %v", err)
+	}
+
+	for _, call := range calls {
+		if strings.Contains(call, "gho_secret") {
+			t.Errorf("Expected the token to stay out of the arguments, got %q", call)
+		}
+	}
+}
+
+func TestSecretServiceStoreLocked(t *testing.T) {
+	store := &SecretServiceStore{run: func(stdin string, arg ...string) ([]byte, error) {
+		return nil, exitError(t, 1, "secret-tool: Cannot create an item in a locked collection")
+	}}
+	_, err := store.Get("github.com")
+	if err == nil || errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected a locked keyring to fail, got %v", err)
+	}
+}
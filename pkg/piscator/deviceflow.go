@@ -0,0 +1,146 @@
+package piscator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrDeviceCodeExpired is returned when the user didn't authorize the device
+// before its code expired
+var ErrDeviceCodeExpired = errors.New("the device code expired before it was authorized")
+
+// ErrAccessDenied is returned when the user declined the authorization
+var ErrAccessDenied = errors.New("the authorization was denied")
+
+// added to the polling interval every time GitHub answers slow_down
+const deviceSlowDown = 5 * time.Second
+
+// DeviceCode is what the user needs to authorize piscator in a browser
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// DeviceFlow logs in through the OAuth device authorization flow: it asks
+// for a code the user enters at VerificationURI, then polls until they did.
+type DeviceFlow struct {
+	Client  HttpClient
+	Sleeper Sleeper
+	// BaseURL of the forge's web host, https://github.com or a GHES host
+	BaseURL  string
+	ClientID string
+	Scopes   []string
+}
+
+type deviceResponse struct {
+	DeviceCode
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Requests a device and user code to show the user
+func (f *DeviceFlow) RequestCode() (*DeviceCode, error) {
+	res, err := f.post("/login/device/code", url.Values{
+		"client_id": {f.ClientID},
+		"scope":     {strings.Join(f.Scopes, " ")},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("error requesting a device code: %s", res.describe())
+	}
+	return &res.DeviceCode, nil
+}
+
+// Polls for the access token until the user authorized the device, waiting
+// the interval GitHub asks for and backing off when told to slow down.
+func (f *DeviceFlow) PollToken(code *DeviceCode) (string, error) {
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = deviceSlowDown
+	}
+	remaining := time.Duration(code.ExpiresIn) * time.Second
+
+	for {
+		if code.ExpiresIn > 0 && remaining < interval {
+			return "", ErrDeviceCodeExpired
+		}
+		f.Sleeper.Sleep(interval)
+		remaining -= interval
+
+		res, err := f.post("/login/oauth/access_token", url.Values{
+			"client_id":   {f.ClientID},
+			"device_code": {code.DeviceCode},
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		})
+		if err != nil {
+			return "", err
+		}
+
+		switch res.Error {
+		case "":
+			if res.AccessToken == "" {
+				return "", errors.New("no access token in the response")
+			}
+			return res.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			// GitHub sends the new interval, otherwise add the 5s RFC 8628 asks for
+			if res.Interval > 0 {
+				interval = time.Duration(res.Interval) * time.Second
+			} else {
+				interval += deviceSlowDown
+			}
+		case "expired_token":
+			return "", ErrDeviceCodeExpired
+		case "access_denied":
+			return "", ErrAccessDenied
+		default:
+			return "", fmt.Errorf("error polling for the access token: %s", res.describe())
+		}
+	}
+}
+
+func (f *DeviceFlow) post(path string, form url.Values) (*deviceResponse, error) {
+	endpoint := strings.TrimSuffix(f.BaseURL, "/") + path
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "shimman-dev/piscator")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// errors come back as 200s with an error field, anything else is unexpected
+	if res.StatusCode >= 300 {
+		return nil, &StatusError{URL: endpoint, StatusCode: res.StatusCode}
+	}
+	var body deviceResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	return &body, nil
+}
+
+func (r *deviceResponse) describe() string {
+	if r.ErrorDescription != "" {
+		return r.ErrorDescription
+	}
+	return r.Error
+}
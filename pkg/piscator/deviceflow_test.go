@@ -0,0 +1,114 @@
+package piscator
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Stands in for GitHub's OAuth endpoints, answering the token polls in order
+func newDeviceServer(t *testing.T, polls []string) *httptest.Server {
+	i := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/device/code", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("client_id") != "client" || r.Form.Get("scope") != "repo read:org" {
+			t.Errorf("Unexpected device code request %v", r.Form)
+		}
+		fmt.Fprint(w, `{"device_code": "dev123", "user_code": "ABCD-1234", "verification_uri": "https://github.com/login/device", "expires_in": 900, "interval": 5}`)
+	})
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("device_code") != "dev123" || r.Form.Get("grant_type") != "urn:ietf:params:oauth:grant-type:device_code" {
+			t.Errorf("Unexpected token request %v", r.Form)
+		}
+		fmt.Fprint(w, polls[i])
+		i++
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDeviceFlow(t *testing.T) {
+	server := newDeviceServer(t, []string{
+		`{"error": "authorization_pending"}`,
+		`{"error": "slow_down", "interval": 10}`,
+		`{"error": "slow_down"}`,
+		`{"access_token": "gho_device", "token_type": "bearer", "scope": "repo,read:org"}`,
+	})
+	sleeper := &MockSleeper{}
+	flow := &DeviceFlow{Client: server.Client(), Sleeper: sleeper, BaseURL: server.URL, ClientID: "client", Scopes: []string{"repo", "read:org"}}
+
+	code, err := flow.RequestCode()
+	if err != nil {
+		t.Fatalf("RequestCode() error = %v", err)
+	}
+	if code.UserCode != "ABCD-1234" || code.VerificationURI != "https://github.com/login/device" {
+		t.Errorf("Unexpected device code %+v", code)
+	}
+
+	token, err := flow.PollToken(code)
+	if err != nil || token != "gho_device" {
+		t.Fatalf("Expected gho_device, got %q (%v)", token, err)
+	}
+
+	// slow_down uses the interval sent back, or adds five seconds
+	expected := []time.Duration{5 * time.Second, 5 * time.Second, 10 * time.Second, 15 * time.Second}
+	if !reflect.DeepEqual(sleeper.Durations, expected) {
+		t.Errorf("Expected waits %v, got %v", expected, sleeper.Durations)
+	}
+}
+
+func TestDeviceFlowErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		polls   []string
+		wantErr error
+	}{
+		{"denied", []string{`{"error": "access_denied"}`}, ErrAccessDenied},
+		{"expired", []string{`{"error": "expired_token"}`}, ErrDeviceCodeExpired},
+		{"unknown", []string{`{"error": "incorrect_client_credentials", "error_description": "The client_id is not valid."}`}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newDeviceServer(t, tt.polls)
+			flow := &DeviceFlow{Client: server.Client(), Sleeper: &MockSleeper{}, BaseURL: server.URL, ClientID: "client", Scopes: []string{"repo", "read:org"}}
+			code, err := flow.RequestCode()
+			if err != nil {
+				t.Fatalf("RequestCode() error = %v", err)
+			}
+			_, err = flow.PollToken(code)
+			if err == nil {
+				t.Fatalf("Expected an error but did not get one")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestDeviceFlowStopsAtExpiry(t *testing.T) {
+	polls := make([]string, 10)
+	for i := range polls {
+		polls[i] = `{"error": "authorization_pending"}`
+	}
+	server := newDeviceServer(t, polls)
+	sleeper := &MockSleeper{}
+	flow := &DeviceFlow{Client: server.Client(), Sleeper: sleeper, BaseURL: server.URL, ClientID: "client"}
+
+	_, err := flow.PollToken(&DeviceCode{DeviceCode: "dev123", ExpiresIn: 12, Interval: 5})
+	if !errors.Is(err, ErrDeviceCodeExpired) {
+		t.Errorf("Expected error %v, got %v", ErrDeviceCodeExpired, err)
+	}
+	if len(sleeper.Durations) != 2 {
+		t.Errorf("Expected two polls before the code expired, got %d", len(sleeper.Durations))
+	}
+}
@@ -0,0 +1,163 @@
+package piscator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of changes reported by DiffRepos
+const (
+	ChangeAdded      = "added"
+	ChangeRemoved    = "removed"
+	ChangeRenamed    = "renamed"
+	ChangeVisibility = "visibility"
+	ChangeArchived   = "archived"
+	ChangeLanguage   = "language"
+)
+
+var changeOrder = map[string]int{
+	ChangeAdded:      0,
+	ChangeRemoved:    1,
+	ChangeRenamed:    2,
+	ChangeVisibility: 3,
+	ChangeArchived:   4,
+	ChangeLanguage:   5,
+}
+
+// RepoChange describes a single difference between two repo listings
+type RepoChange struct {
+	Kind string `json:"kind"`
+	ID   int64  `json:"id,omitempty"`
+	Name string `json:"name"`
+	URL  string `json:"html_url"`
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// Compares two repo listings, as produced by GetRepos, and returns what changed
+// between them. Repos are matched by their GitHub id so renames are detected,
+// falling back to the name for snapshots taken before ids were recorded.
+func DiffRepos(oldJSON, newJSON string) ([]RepoChange, error) {
+	var oldRepos, newRepos []RepoModel
+	if err := json.Unmarshal([]byte(oldJSON), &oldRepos); err != nil {
+		return nil, fmt.Errorf("error reading old snapshot: %w", err)
+	}
+	if err := json.Unmarshal([]byte(newJSON), &newRepos); err != nil {
+		return nil, fmt.Errorf("error reading new snapshot: %w", err)
+	}
+
+	oldByKey := make(map[string]RepoModel, len(oldRepos))
+	for _, repo := range oldRepos {
+		oldByKey[repoKey(repo)] = repo
+	}
+
+	changes := []RepoChange{}
+	seen := make(map[string]bool, len(newRepos))
+
+	for _, repo := range newRepos {
+		key := repoKey(repo)
+		prev, ok := oldByKey[key]
+		if !ok && repo.ID != 0 {
+			// the old snapshot may predate ids, try matching by name instead
+			prev, ok = oldByKey["name:"+repo.Name]
+			if ok {
+				key = "name:" + repo.Name
+			}
+		}
+		if !ok {
+			changes = append(changes, RepoChange{Kind: ChangeAdded, ID: repo.ID, Name: repo.Name, URL: repo.URL})
+			continue
+		}
+		seen[key] = true
+
+		change := RepoChange{ID: repo.ID, Name: repo.Name, URL: repo.URL}
+		if prev.Name != repo.Name {
+			change.Kind, change.From, change.To = ChangeRenamed, prev.Name, repo.Name
+			changes = append(changes, change)
+		}
+		if from, to := visibilityOf(prev), visibilityOf(repo); from != to {
+			change.Kind, change.From, change.To = ChangeVisibility, from, to
+			changes = append(changes, change)
+		}
+		if prev.Archived != repo.Archived {
+			change.Kind, change.From, change.To = ChangeArchived, strconv.FormatBool(prev.Archived), strconv.FormatBool(repo.Archived)
+			changes = append(changes, change)
+		}
+		if prev.Lang != repo.Lang {
+			change.Kind, change.From, change.To = ChangeLanguage, prev.Lang, repo.Lang
+			changes = append(changes, change)
+		}
+	}
+
+	for key, repo := range oldByKey {
+		if !seen[key] {
+			changes = append(changes, RepoChange{Kind: ChangeRemoved, ID: repo.ID, Name: repo.Name, URL: repo.URL})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changeOrder[changes[i].Kind] < changeOrder[changes[j].Kind]
+		}
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes, nil
+}
+
+func repoKey(repo RepoModel) string {
+	if repo.ID != 0 {
+		return "id:" + strconv.FormatInt(repo.ID, 10)
+	}
+	return "name:" + repo.Name
+}
+
+// Older snapshots only carry the private flag
+func visibilityOf(repo RepoModel) string {
+	if repo.Visibility != "" {
+		return repo.Visibility
+	}
+	if repo.Private {
+		return "private"
+	}
+	return "public"
+}
+
+// Formats a changelog from DiffRepos as human readable text.
+func FormatChanges(changes []RepoChange) string {
+	if len(changes) == 0 {
+		return "No changes\n"
+	}
+
+	var b strings.Builder
+	var added, removed, changed int
+	for _, c := range changes {
+		switch c.Kind {
+		case ChangeAdded:
+			added++
+			fmt.Fprintf(&b, "+ %s (%s)\n", c.Name, c.URL)
+		case ChangeRemoved:
+			removed++
+			fmt.Fprintf(&b, "- %s (%s)\n", c.Name, c.URL)
+		case ChangeRenamed:
+			changed++
+			fmt.Fprintf(&b, "~ %s renamed from %s\n", c.To, c.From)
+		default:
+			changed++
+			fmt.Fprintf(&b, "~ %s %s: %s -> %s\n", c.Name, c.Kind, orNone(c.From), orNone(c.To))
+		}
+	}
+	fmt.Fprintf(&b, "\n%d added, %d removed, %d changed\n", added, removed, changed)
+
+	return b.String()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
@@ -0,0 +1,102 @@
+package piscator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRepos(t *testing.T) {
+	oldJSON := `[
+		{"id": 1, "name": "kept", "html_url": "url1", "language": "Go", "visibility": "public"},
+		{"id": 2, "name": "old-name", "html_url": "url2", "language": "Go", "visibility": "public"},
+		{"id": 3, "name": "gone", "html_url": "url3", "language": "Go", "visibility": "public"},
+		{"id": 4, "name": "shifting", "html_url": "url4", "language": "Go", "visibility": "public"}
+	]`
+	newJSON := `[
+		{"id": 1, "name": "kept", "html_url": "url1", "language": "Go", "visibility": "public"},
+		{"id": 2, "name": "new-name", "html_url": "url2", "language": "Go", "visibility": "public"},
+		{"id": 4, "name": "shifting", "html_url": "url4", "language": "Rust", "visibility": "private", "archived": true},
+		{"id": 5, "name": "fresh", "html_url": "url5", "language": "Go", "visibility": "public"}
+	]`
+
+	tests := []struct {
+		name      string
+		oldJSON   string
+		newJSON   string
+		expected  []RepoChange
+		wantError bool
+	}{
+		{
+			name:    "changes",
+			oldJSON: oldJSON,
+			newJSON: newJSON,
+			expected: []RepoChange{
+				{Kind: ChangeAdded, ID: 5, Name: "fresh", URL: "url5"},
+				{Kind: ChangeRemoved, ID: 3, Name: "gone", URL: "url3"},
+				{Kind: ChangeRenamed, ID: 2, Name: "new-name", URL: "url2", From: "old-name", To: "new-name"},
+				{Kind: ChangeVisibility, ID: 4, Name: "shifting", URL: "url4", From: "public", To: "private"},
+				{Kind: ChangeArchived, ID: 4, Name: "shifting", URL: "url4", From: "false", To: "true"},
+				{Kind: ChangeLanguage, ID: 4, Name: "shifting", URL: "url4", From: "Go", To: "Rust"},
+			},
+		},
+		{
+			name:     "no changes",
+			oldJSON:  oldJSON,
+			newJSON:  oldJSON,
+			expected: []RepoChange{},
+		},
+		{
+			name:     "snapshot without ids",
+			oldJSON:  `[{"name": "repo1", "html_url": "url1", "private": true}]`,
+			newJSON:  `[{"id": 9, "name": "repo1", "html_url": "url1", "visibility": "private"}]`,
+			expected: []RepoChange{},
+		},
+		{
+			name:      "invalid old snapshot",
+			oldJSON:   `{[}`,
+			newJSON:   `[]`,
+			wantError: true,
+		},
+		{
+			name:      "invalid new snapshot",
+			oldJSON:   `[]`,
+			newJSON:   `{[}`,
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffRepos(tt.oldJSON, tt.newJSON)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("DiffRepos() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatChanges(t *testing.T) {
+	changes := []RepoChange{
+		{Kind: ChangeAdded, Name: "fresh", URL: "url5"},
+		{Kind: ChangeRenamed, Name: "new-name", From: "old-name", To: "new-name"},
+		{Kind: ChangeLanguage, Name: "shifting", From: "", To: "Rust"},
+	}
+
+	got := FormatChanges(changes)
+	for _, want := range []string{"+ fresh (url5)", "~ new-name renamed from old-name", "~ shifting language: none -> Rust", "1 added, 0 removed, 2 changed"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, got)
+		}
+	}
+
+	if got := FormatChanges(nil); got != "No changes\n" {
+		t.Errorf("Expected no changes message, got %q", got)
+	}
+}
@@ -0,0 +1,123 @@
+package piscator
+
+import "time"
+
+// Event is emitted to an Observer while listing or cloning repositories. It is
+// one of ListPageFetched, RequestRetried, RateLimited, ReelStarted,
+// RepoStarted, RepoProgress, RepoFinished or ReelFinished.
+type Event interface {
+	isEvent()
+}
+
+// ListPageFetched is emitted after each page of a repository listing is fetched
+type ListPageFetched struct {
+	URL   string
+	Page  int
+	Count int // repos on this page
+}
+
+// RequestRetried is emitted when a GitHub API request failed and is retried
+type RequestRetried struct {
+	URL        string
+	Attempt    int
+	StatusCode int // zero when the request itself failed
+	Err        error
+}
+
+// RateLimited is emitted when GitHub's rate limit forces a wait
+type RateLimited struct {
+	URL   string
+	Until time.Time
+	Wait  time.Duration
+}
+
+// ReelStarted is emitted before any repository is cloned
+type ReelStarted struct {
+	Dir   string
+	Total int
+}
+
+// RepoStarted is emitted when a worker starts cloning or pulling a repository
+type RepoStarted struct {
+	Repo Repo
+}
+
+// RepoProgress is emitted as git reports progress for a repository
+type RepoProgress struct {
+	Repo    Repo
+	Phase   string
+	Percent int
+}
+
+// RepoFinished is emitted when a repository has been cloned or pulled
+type RepoFinished struct {
+	Repo   Repo
+	Result RepoResult
+}
+
+// ReelFinished is emitted once every repository has finished
+type ReelFinished struct {
+	Total  int
+	Failed int
+}
+
+func (ListPageFetched) isEvent() {}
+func (RequestRetried) isEvent()  {}
+func (RateLimited) isEvent()     {}
+func (ReelStarted) isEvent()     {}
+func (RepoStarted) isEvent()     {}
+func (RepoProgress) isEvent()    {}
+func (RepoFinished) isEvent()    {}
+func (ReelFinished) isEvent()    {}
+
+// Actions reported in RepoResult.Action
+const (
+	ActionClone = "clone"
+	ActionPull  = "pull"
+)
+
+// RepoResult is the outcome of cloning or pulling a single repository
+type RepoResult struct {
+	Action   string
+	Output   []byte
+	Duration time.Duration
+	Err      error
+}
+
+// Observer receives events while listing and cloning. Events are emitted from
+// several goroutines at once, so Observe must be safe for concurrent use.
+type Observer interface {
+	Observe(event Event)
+}
+
+// ObserverFunc adapts a function to the Observer interface
+type ObserverFunc func(event Event)
+
+func (f ObserverFunc) Observe(event Event) {
+	f(event)
+}
+
+// Returns an Observer that sends every event to ch. The caller must keep
+// receiving from ch until the listing or reel returns.
+func ChannelObserver(ch chan<- Event) Observer {
+	return ObserverFunc(func(event Event) {
+		ch <- event
+	})
+}
+
+// Returns an Observer that forwards every event to each of observers in turn,
+// nil observers are skipped.
+func Observers(observers ...Observer) Observer {
+	return ObserverFunc(func(event Event) {
+		for _, observer := range observers {
+			notify(observer, event)
+		}
+	})
+}
+
+// Sends event to observer, a nil observer ignores every event
+func notify(observer Observer, event Event) {
+	if observer != nil {
+		observer.Observe(event)
+	}
+}
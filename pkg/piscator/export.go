@@ -0,0 +1,188 @@
+package piscator
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ExportFormats are the multi-repo tool configs ExportRepos writes, myrepos is
+// another name for mrconfig
+var ExportFormats = []string{"repo-manifest", "gitmodules", "mrconfig", "myrepos", "gita"}
+
+// Writes repos as the config of another multi-repo tool, so a listing can be
+// handed to it instead of reeled:
+//
+//   - repo-manifest, an Android repo XML manifest
+//   - gitmodules, the .gitmodules of a superproject
+//   - mrconfig or myrepos, a .mrconfig for myrepos
+//   - gita, the url,name,path CSV gita clone -f reads
+//
+// Repos are placed under dir like CloneReposFromJson would, an empty dir is
+// relative to wherever the tool runs.
+func ExportRepos(w io.Writer, repos []RepoModel, format, dir string) error {
+	switch format {
+	case "repo-manifest":
+		return exportManifest(w, repos, dir)
+	case "gitmodules":
+		return exportGitmodules(w, repos, dir)
+	case "mrconfig", "myrepos":
+		return exportMrconfig(w, repos, dir)
+	case "gita":
+		return exportGita(w, repos, dir)
+	default:
+		return fmt.Errorf("invalid export format %q, expected one of %s", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+type manifest struct {
+	XMLName  xml.Name          `xml:"manifest"`
+	Remotes  []manifestRemote  `xml:"remote"`
+	Default  manifestDefault   `xml:"default"`
+	Projects []manifestProject `xml:"project"`
+}
+
+type manifestRemote struct {
+	Name  string `xml:"name,attr"`
+	Fetch string `xml:"fetch,attr"`
+}
+
+type manifestDefault struct {
+	Remote   string `xml:"remote,attr"`
+	Revision string `xml:"revision,attr"`
+	SyncJ    int    `xml:"sync-j,attr"`
+}
+
+type manifestProject struct {
+	Name     string `xml:"name,attr"`
+	Path     string `xml:"path,attr"`
+	Remote   string `xml:"remote,attr,omitempty"`
+	Revision string `xml:"revision,attr,omitempty"`
+}
+
+var remoteNameInvalid = regexp.MustCompile(`[^a-z0-9]+`)
+
+// repo fetches a project from its remote's fetch URL joined with the project
+// name, so every URL is split at its last path segment and repos sharing the
+// same prefix, usually an owner, share a remote.
+func exportManifest(w io.Writer, repos []RepoModel, dir string) error {
+	m := manifest{Default: manifestDefault{Revision: "main", SyncJ: 4}}
+	remotes := map[string]string{}
+	used := map[string]bool{}
+
+	for _, repo := range repos {
+		repoPath, cloneURL := cloneTarget(dir, repo.Repo)
+		i := strings.LastIndex(cloneURL, "/")
+		if i < 0 {
+			return fmt.Errorf("can't split %s into a remote and project name", cloneURL)
+		}
+		fetch, name := cloneURL[:i], cloneURL[i+1:]
+
+		remote, ok := remotes[fetch]
+		if !ok {
+			base := strings.Trim(remoteNameInvalid.ReplaceAllString(strings.ToLower(hostPath(fetch)), "-"), "-")
+			remote = base
+			for n := 2; used[remote]; n++ {
+				remote = fmt.Sprintf("%s-%d", base, n)
+			}
+			used[remote] = true
+			remotes[fetch] = remote
+			m.Remotes = append(m.Remotes, manifestRemote{Name: remote, Fetch: fetch})
+		}
+		if m.Default.Remote == "" {
+			m.Default.Remote = remote
+		}
+
+		project := manifestProject{Name: name, Path: repoPath, Revision: repoBranch(repo)}
+		if remote != m.Default.Remote {
+			project.Remote = remote
+		}
+		m.Projects = append(m.Projects, project)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// Strips the scheme and user from a URL, leaving the host and path a remote
+// is named after
+func hostPath(rawURL string) string {
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		rawURL = rawURL[i+3:]
+	}
+	if i := strings.Index(rawURL, "@"); i >= 0 {
+		rawURL = rawURL[i+1:]
+	}
+	return rawURL
+}
+
+// The branch a listing pins a repo to, or the one GitHub reported as default
+func repoBranch(repo RepoModel) string {
+	if repo.Branch != "" {
+		return repo.Branch
+	}
+	return repo.DefaultBranch
+}
+
+func exportGitmodules(w io.Writer, repos []RepoModel, dir string) error {
+	for _, repo := range repos {
+		repoPath, cloneURL := cloneTarget(dir, repo.Repo)
+		if _, err := fmt.Fprintf(w, "[submodule %q]\n\tpath = %s\n\turl = %s\n", repoPath, repoPath, cloneURL); err != nil {
+			return err
+		}
+		if repo.Branch != "" {
+			if _, err := fmt.Fprintf(w, "\tbranch = %s\n", repo.Branch); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// myrepos runs checkout from the parent of the section's path
+func exportMrconfig(w io.Writer, repos []RepoModel, dir string) error {
+	for i, repo := range repos {
+		repoPath, cloneURL := cloneTarget(dir, repo.Repo)
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		clone := "git clone"
+		if repo.Branch != "" {
+			clone += " --branch " + shellQuote(repo.Branch)
+		}
+		if _, err := fmt.Fprintf(w, "[%s]\ncheckout = %s %s %s\n", repoPath, clone, shellQuote(cloneURL), shellQuote(repo.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func exportGita(w io.Writer, repos []RepoModel, dir string) error {
+	cw := csv.NewWriter(w)
+	for _, repo := range repos {
+		repoPath, cloneURL := cloneTarget(dir, repo.Repo)
+		if err := cw.Write([]string{cloneURL, repo.displayName(), repoPath}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,160 @@
+package piscator
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+var exportRepos = []RepoModel{
+	{Repo: Repo{Name: "piscator", URL: "https://github.com/shimman-dev/piscator"}, DefaultBranch: "main"},
+	{Repo: Repo{Name: "dotfiles", URL: "https://github.com/octocat/dotfiles", Branch: "stable"}, DefaultBranch: "master"},
+	{Repo: Repo{Name: "tools", URL: "git@git.example.com:team/tools.git"}},
+	{Repo: Repo{Name: "aa11", URL: "https://gist.github.com/aa11", GitPullURL: "https://gist.github.com/aa11.git", Gist: true}},
+}
+
+func TestExportRepos(t *testing.T) {
+	tests := []struct {
+		format string
+		dir    string
+		want   string
+	}{
+		{
+			format: "repo-manifest",
+			want: `<?xml version="1.0" encoding="UTF-8"?>
+<manifest>
+  <remote name="github-com-shimman-dev" fetch="https://github.com/shimman-dev"></remote>
+  <remote name="github-com-octocat" fetch="https://github.com/octocat"></remote>
+  <remote name="git-example-com-team" fetch="git@git.example.com:team"></remote>
+  <remote name="gist-github-com" fetch="https://gist.github.com"></remote>
+  <default remote="github-com-shimman-dev" revision="main" sync-j="4"></default>
+  <project name="piscator" path="piscator" revision="main"></project>
+  <project name="dotfiles" path="dotfiles" remote="github-com-octocat" revision="stable"></project>
+  <project name="tools.git" path="tools" remote="git-example-com-team"></project>
+  <project name="aa11.git" path="gists/aa11" remote="gist-github-com"></project>
+</manifest>
+`,
+		},
+		{
+			format: "gitmodules",
+			dir:    "vendor",
+			want: `[submodule "vendor/piscator"]
+	path = vendor/piscator
+	url = https://github.com/shimman-dev/piscator
+[submodule "vendor/dotfiles"]
+	path = vendor/dotfiles
+	url = https://github.com/octocat/dotfiles
+	branch = stable
+[submodule "vendor/tools"]
+	path = vendor/tools
+	url = git@git.example.com:team/tools.git
+[submodule "vendor/gists/aa11"]
+	path = vendor/gists/aa11
+	url = https://gist.github.com/aa11.git
+`,
+		},
+		{
+			format: "myrepos",
+			want: `[piscator]
+checkout = git clone 'https://github.com/shimman-dev/piscator' 'piscator'
+
+[dotfiles]
+checkout = git clone --branch 'stable' 'https://github.com/octocat/dotfiles' 'dotfiles'
+
+[tools]
+checkout = git clone 'git@git.example.com:team/tools.git' 'tools'
+
+[gists/aa11]
+checkout = git clone 'https://gist.github.com/aa11.git' 'aa11'
+`,
+		},
+		{
+			format: "gita",
+			dir:    "/src",
+			want: `https://github.com/shimman-dev/piscator,piscator,/src/piscator
+https://github.com/octocat/dotfiles,dotfiles,/src/dotfiles
+git@git.example.com:team/tools.git,tools,/src/tools
+https://gist.github.com/aa11.git,aa11,/src/gists/aa11
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := ExportRepos(&b, exportRepos, tt.format, tt.dir); err != nil {
+				t.Fatalf("ExportRepos() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Expected\n%s\ngot\n%s", tt.want, b.String())
+			}
+		})
+	}
+}
+
+func TestExportReposByOwner(t *testing.T) {
+	repos := []RepoModel{
+		{Repo: Repo{Name: "cobra", URL: "https://github.com/spf13/cobra", ByOwner: true}},
+		{Repo: Repo{Name: "cobra", URL: "https://github.com/octocat/cobra", ByOwner: true}},
+	}
+
+	tests := map[string]string{
+		"gitmodules": `[submodule "spf13/cobra"]
+	path = spf13/cobra
+	url = https://github.com/spf13/cobra
+[submodule "octocat/cobra"]
+	path = octocat/cobra
+	url = https://github.com/octocat/cobra
+`,
+		"myrepos": `[spf13/cobra]
+checkout = git clone 'https://github.com/spf13/cobra' 'cobra'
+
+[octocat/cobra]
+checkout = git clone 'https://github.com/octocat/cobra' 'cobra'
+`,
+		"gita": `https://github.com/spf13/cobra,spf13/cobra,spf13/cobra
+https://github.com/octocat/cobra,octocat/cobra,octocat/cobra
+`,
+	}
+
+	for format, want := range tests {
+		var b bytes.Buffer
+		if err := ExportRepos(&b, repos, format, ""); err != nil {
+			t.Fatalf("ExportRepos(%s) error = %v", format, err)
+		}
+		if b.String() != want {
+			t.Errorf("Expected\n%s\ngot\n%s", want, b.String())
+		}
+	}
+}
+
+func TestExportReposErrors(t *testing.T) {
+	var b bytes.Buffer
+	if err := ExportRepos(&b, exportRepos, "vcstool", ""); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+	if err := ExportRepos(&b, []RepoModel{{Repo: Repo{Name: "x", URL: "x"}}}, "repo-manifest", ""); err == nil {
+		t.Errorf("Expected an error for a URL without a path")
+	}
+}
+
+// failingWriter fails every write, like a full disk or a closed pipe
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("no space left on device")
+}
+
+func TestExportReposWriteErrors(t *testing.T) {
+	for _, format := range ExportFormats {
+		if err := ExportRepos(failingWriter{}, exportRepos, format, ""); err == nil {
+			t.Errorf("Expected %s to report the failed write", format)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	if got := shellQuote("it's"); got != `'it'\''s'` {
+		t.Errorf("Expected quoted string, got %s", got)
+	}
+}
@@ -0,0 +1,318 @@
+package piscator
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OutputFormats are the formats WriteRepos writes
+var OutputFormats = []string{"json", "jsonl", "yaml", "csv", "tsv", "table", "urls"}
+
+// DefaultFields are the columns of the csv, tsv and table formats when no
+// fields are chosen
+var DefaultFields = []string{"name", "html_url", "language", "visibility", "pushed_at"}
+
+// field names accepted in place of the JSON keys
+var fieldAliases = map[string]string{
+	"url": "html_url",
+}
+
+// Returns the fields a repo can be printed with, the JSON keys of RepoModel in
+// the order they're declared
+func RepoFields() []string {
+	var fields []string
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous {
+				walk(f.Type)
+				continue
+			}
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name != "" && name != "-" {
+				fields = append(fields, name)
+			}
+		}
+	}
+	walk(reflect.TypeOf(RepoModel{}))
+	return fields
+}
+
+// Resolves aliases and checks every field exists, an empty list is every
+// field
+func resolveFields(fields []string) ([]string, error) {
+	known := RepoFields()
+	if len(fields) == 0 {
+		return known, nil
+	}
+
+	resolved := make([]string, 0, len(fields))
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if alias, ok := fieldAliases[field]; ok {
+			field = alias
+		}
+		found := false
+		for _, k := range known {
+			found = found || k == field
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q, expected one of %s", field, strings.Join(known, ", "))
+		}
+		resolved = append(resolved, field)
+	}
+	return resolved, nil
+}
+
+// repoRecord holds the chosen fields of a repo, encoded in their order
+type repoRecord struct {
+	keys   []string
+	values map[string]any
+}
+
+func newRepoRecord(repo RepoModel, fields []string) (repoRecord, error) {
+	data, err := json.Marshal(repo)
+	if err != nil {
+		return repoRecord{}, err
+	}
+	values := map[string]any{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return repoRecord{}, err
+	}
+	if fields == nil {
+		// every field the repo has, without the empty ones JSON omits
+		for _, key := range RepoFields() {
+			if _, ok := values[key]; ok {
+				fields = append(fields, key)
+			}
+		}
+	}
+	return repoRecord{keys: fields, values: values}, nil
+}
+
+func (r repoRecord) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range r.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(r.values[key])
+		if err != nil {
+			return nil, err
+		}
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(v)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+func (r repoRecord) MarshalYAML() (any, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range r.keys {
+		var value yaml.Node
+		if err := value.Encode(r.values[key]); err != nil {
+			return nil, err
+		}
+		node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &value)
+	}
+	return node, nil
+}
+
+// Formats a value for a csv, tsv or table cell. Lists are joined with ;
+// and a license is shown by its SPDX id.
+func (r repoRecord) cell(key string) string {
+	switch v := r.values[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ";")
+	case map[string]any:
+		if id, ok := v["spdx_id"].(string); ok {
+			return id
+		}
+		data, _ := json.Marshal(v)
+		return string(data)
+	case float64:
+		// JSON numbers decode as floats, ids and sizes are whole
+		return fmt.Sprint(int64(v))
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Writes repos from a JSON string, such as the one GetRepos returns, in one
+// of OutputFormats. fields chooses and orders the keys or columns printed,
+// DefaultFields are the columns of csv, tsv and table and every field is
+// printed otherwise. urls prints one URL per line and ignores fields.
+func WriteRepos(w io.Writer, jsonStr, format string, fields []string) error {
+	return writeRepos(w, jsonStr, format, fields, nil)
+}
+
+// Writes repos like WriteRepos, preceded by meta. json and yaml wrap the repos
+// in an object with metadata and repos keys, the other formats start with #
+// comment lines, which reel skips. jsonl has no room for metadata.
+func WriteReposWithMetadata(w io.Writer, jsonStr, format string, fields []string, meta RepoFileMetadata) error {
+	return writeRepos(w, jsonStr, format, fields, &meta)
+}
+
+func writeRepos(w io.Writer, jsonStr, format string, fields []string, meta *RepoFileMetadata) error {
+	var repos []RepoModel
+	if err := json.Unmarshal([]byte(jsonStr), &repos); err != nil {
+		return err
+	}
+
+	switch format {
+	case "csv", "tsv", "table":
+		if len(fields) == 0 {
+			fields = DefaultFields
+		}
+	case "jsonl":
+		if meta != nil {
+			return fmt.Errorf("jsonl output can't hold metadata")
+		}
+	case "json", "yaml", "urls":
+	default:
+		return fmt.Errorf("invalid output format %q, expected one of %s", format, strings.Join(OutputFormats, ", "))
+	}
+
+	keys, err := resolveFields(fields)
+	if err != nil {
+		return err
+	}
+	records := make([]repoRecord, len(repos))
+	for i, repo := range repos {
+		chosen := keys
+		if len(fields) == 0 {
+			chosen = nil
+		}
+		if records[i], err = newRepoRecord(repo, chosen); err != nil {
+			return err
+		}
+	}
+
+	if meta != nil && format != "json" && format != "yaml" {
+		if _, err := io.WriteString(w, meta.comment()); err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "json":
+		var v any = records
+		if meta != nil {
+			v = repoFile{Metadata: meta, Repos: records}
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		var v any = records
+		if meta != nil {
+			v = repoFile{Metadata: meta, Repos: records}
+		}
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "urls":
+		for _, repo := range repos {
+			if _, err := fmt.Fprintln(w, repo.URL); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		header := make([]string, len(keys))
+		for i, key := range keys {
+			header[i] = strings.ToUpper(key)
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, record := range records {
+			row := make([]string, len(keys))
+			for i, key := range keys {
+				row[i] = record.cell(key)
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	default:
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(keys); err != nil {
+			return err
+		}
+		for _, record := range records {
+			row := make([]string, len(keys))
+			for i, key := range keys {
+				row[i] = record.cell(key)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+}
+
+// Writes repos from a JSON string through a Go template executed once per
+// repo, such as '{{.Name}} {{.URL}}'. A newline follows every repo unless the
+// template ends with one.
+func WriteReposTemplate(w io.Writer, jsonStr, text string) error {
+	var repos []RepoModel
+	if err := json.Unmarshal([]byte(jsonStr), &repos); err != nil {
+		return err
+	}
+
+	tmpl, err := template.New("repo").Funcs(template.FuncMap{"join": strings.Join}).Parse(text)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+	for _, repo := range repos {
+		if err := tmpl.Execute(w, repo); err != nil {
+			return err
+		}
+		if !strings.HasSuffix(text, "\n") {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package piscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const formatRepos = `[
+  {"name": "piscator", "html_url": "https://github.com/shimman-dev/piscator", "id": 1, "language": "Go", "visibility": "public", "topics": ["cli", "git"], "license": {"key": "mit", "name": "MIT License", "spdx_id": "MIT"}},
+  {"name": "notes, misc", "html_url": "https://github.com/shimman-dev/notes", "id": 2, "language": "", "private": true, "visibility": "private"}
+]`
+
+func TestWriteRepos(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		fields []string
+		want   string
+	}{
+		{
+			name:   "urls",
+			format: "urls",
+			want:   "https://github.com/shimman-dev/piscator\nhttps://github.com/shimman-dev/notes\n",
+		},
+		{
+			name:   "csv default fields",
+			format: "csv",
+			want:   "name,html_url,language,visibility,pushed_at\npiscator,https://github.com/shimman-dev/piscator,Go,public,\n\"notes, misc\",https://github.com/shimman-dev/notes,,private,\n",
+		},
+		{
+			name:   "tsv chosen fields",
+			format: "tsv",
+			fields: []string{"url", "topics", "license", "id"},
+			want:   "html_url\ttopics\tlicense\tid\nhttps://github.com/shimman-dev/piscator\tcli;git\tMIT\t1\nhttps://github.com/shimman-dev/notes\t\t\t2\n",
+		},
+		{
+			name:   "table",
+			format: "table",
+			fields: []string{"name", "private"},
+			want:   "NAME         PRIVATE\npiscator     false\nnotes, misc  true\n",
+		},
+		{
+			name:   "jsonl chosen fields",
+			format: "jsonl",
+			fields: []string{"name", "url"},
+			want:   "{\"name\":\"piscator\",\"html_url\":\"https://github.com/shimman-dev/piscator\"}\n{\"name\":\"notes, misc\",\"html_url\":\"https://github.com/shimman-dev/notes\"}\n",
+		},
+		{
+			name:   "yaml chosen fields",
+			format: "yaml",
+			fields: []string{"name", "topics"},
+			want:   "- name: piscator\n  topics:\n    - cli\n    - git\n- name: notes, misc\n  topics: null\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteRepos(&b, formatRepos, tt.format, tt.fields); err != nil {
+				t.Fatalf("WriteRepos() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Expected\n%q\ngot\n%q", tt.want, b.String())
+			}
+		})
+	}
+}
+
+func TestWriteReposJSON(t *testing.T) {
+	// without fields json matches what cast always printed
+	var repos []RepoModel
+	json.Unmarshal([]byte(formatRepos), &repos)
+	want, _ := json.MarshalIndent(repos, "", "  ")
+
+	var b bytes.Buffer
+	if err := WriteRepos(&b, formatRepos, "json", nil); err != nil {
+		t.Fatalf("WriteRepos() error = %v", err)
+	}
+	if b.String() != string(want)+"\n" {
+		t.Errorf("Expected\n%s\ngot\n%s", want, b.String())
+	}
+}
+
+func TestWriteReposErrors(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteRepos(&b, formatRepos, "xml", nil); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+	if err := WriteRepos(&b, formatRepos, "csv", []string{"name", "stars"}); err == nil || !strings.Contains(err.Error(), `"stars"`) {
+		t.Errorf("Expected an error naming the unknown field, got %v", err)
+	}
+}
+
+func TestWriteReposTemplate(t *testing.T) {
+	var b bytes.Buffer
+	if err := WriteReposTemplate(&b, formatRepos, `{{.Name}} {{.URL}} {{join .Topics ","}}`); err != nil {
+		t.Fatalf("WriteReposTemplate() error = %v", err)
+	}
+	want := "piscator https://github.com/shimman-dev/piscator cli,git\nnotes, misc https://github.com/shimman-dev/notes \n"
+	if b.String() != want {
+		t.Errorf("Expected %q, got %q", want, b.String())
+	}
+
+	if err := WriteReposTemplate(&b, formatRepos, `{{.Name`); err == nil {
+		t.Errorf("Expected an error for an invalid template")
+	}
+}
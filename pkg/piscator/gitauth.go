@@ -0,0 +1,120 @@
+package piscator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// git runs the askpass helper with a prompt such as "Username for
+// 'https://github.com': ", the helper answers from the environment git
+// inherited so credentials never reach a URL, argv or .git/config. Prompts for
+// other hosts, like a submodule elsewhere, get no answer.
+const askPassScript = `#!/bin/sh
+if [ -n "$PISCATOR_GIT_HOST" ]; then
+	case "$1" in
+	*"//$PISCATOR_GIT_HOST"*|*"@$PISCATOR_GIT_HOST"*) ;;
+	*) exit 1 ;;
+	esac
+fi
+case "$1" in
+Username*) printf '%s\n' "$PISCATOR_GIT_USERNAME" ;;
+*) printf '%s\n' "$PISCATOR_GIT_PASSWORD" ;;
+esac
+`
+
+// CredentialExecutor wraps an executor so every git command it runs
+// authenticates with the current token from Source. Tokens are fetched per
+// command, so an expiring token is refreshed during a long reel.
+//
+// The user's credential helpers are switched off for these commands so git
+// neither answers with other credentials nor stores the token in a keychain.
+type CredentialExecutor struct {
+	Executor CommandExecutor
+	// Source supplies the password, a token or the basic auth password
+	Source TokenSource
+	// Username sent alongside the token, GitHub accepts any non-empty name
+	// for tokens and expects "x-access-token" for app installations
+	Username string
+	// Host limits the credentials to one forge host, any host when empty
+	Host string
+
+	dir string
+}
+
+// Creates a CredentialExecutor with an ephemeral askpass helper, Close removes
+// it once the run is over.
+func NewCredentialExecutor(executor CommandExecutor, source TokenSource, username, host string) (*CredentialExecutor, error) {
+	if _, ok := executor.(EnvCommandExecutor); !ok {
+		return nil, errors.New("executor can't pass credentials to git")
+	}
+
+	dir, err := os.MkdirTemp("", "piscator-askpass-")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "askpass.sh"), []byte(askPassScript), 0700); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	return &CredentialExecutor{Executor: executor, Source: source, Username: username, Host: host, dir: dir}, nil
+}
+
+// Removes the askpass helper
+func (c *CredentialExecutor) Close() error {
+	return os.RemoveAll(c.dir)
+}
+
+func (c *CredentialExecutor) helper() string {
+	return filepath.Join(c.dir, "askpass.sh")
+}
+
+// Returns the wrapped executor with the credential environment for one command
+func (c *CredentialExecutor) withCredentials() (CommandExecutor, error) {
+	token, err := c.Source.Token()
+	if err != nil {
+		return nil, err
+	}
+	return c.Executor.(EnvCommandExecutor).WithEnv(
+		"GIT_ASKPASS="+c.helper(),
+		"GIT_TERMINAL_PROMPT=0",
+		// an empty credential.helper resets the list configured by the user
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=credential.helper",
+		"GIT_CONFIG_VALUE_0=",
+		"PISCATOR_GIT_HOST="+c.Host,
+		"PISCATOR_GIT_USERNAME="+c.Username,
+		"PISCATOR_GIT_PASSWORD="+token,
+	), nil
+}
+
+func (c *CredentialExecutor) ExecuteCommand(name string, arg ...string) ([]byte, error) {
+	executor, err := c.withCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return executor.ExecuteCommand(name, arg...)
+}
+
+func (c *CredentialExecutor) ExecuteCommandInDir(dir, name string, arg ...string) ([]byte, error) {
+	executor, err := c.withCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return executor.ExecuteCommandInDir(dir, name, arg...)
+}
+
+func (c *CredentialExecutor) ExecuteCommandStream(dir string, onLine func(line string), name string, arg ...string) ([]byte, error) {
+	executor, err := c.withCredentials()
+	if err != nil {
+		return nil, err
+	}
+	if streamer, ok := executor.(StreamingCommandExecutor); ok {
+		return streamer.ExecuteCommandStream(dir, onLine, name, arg...)
+	}
+	if dir == "" {
+		return executor.ExecuteCommand(name, arg...)
+	}
+	return executor.ExecuteCommandInDir(dir, name, arg...)
+}
@@ -0,0 +1,99 @@
+package piscator
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCredentialExecutor(t *testing.T) {
+	source := &sequenceTokenSource{tokens: []string{"ghs_first", "ghs_second"}}
+	executor, err := NewCredentialExecutor(RealCommandExecutor{}, source, "x-access-token", "github.com")
+	if err != nil {
+		t.Fatalf("NewCredentialExecutor() error = %v", err)
+	}
+
+	// git asks the helper for each part of the credential
+	ask := `"$GIT_ASKPASS" "Username for 'https://github.com': "; "$GIT_ASKPASS" "Password for 'https://x-access-token@github.com': "; "$GIT_ASKPASS" "Password for 'https://gitlab.com': " || echo refused; git config credential.helper; echo "$GIT_TERMINAL_PROMPT"`
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"first token", "x-access-token\nghs_first\nrefused\n\n0\n"},
+		{"refreshed token", "x-access-token\nghs_second\nrefused\n\n0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := executor.ExecuteCommandInDir(t.TempDir(), "sh", "-c", ask)
+			if err != nil {
+				t.Fatalf("ExecuteCommandInDir() error = %v: %s", err, out)
+			}
+			if string(out) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, out)
+			}
+		})
+	}
+
+	script, _ := os.ReadFile(executor.helper())
+	if strings.Contains(string(script), "ghs_") {
+		t.Errorf("Expected the helper not to contain the token")
+	}
+
+	if err := executor.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if _, err := os.Stat(executor.helper()); !os.IsNotExist(err) {
+		t.Errorf("Expected the helper to be removed")
+	}
+}
+
+// EnvRecordingExecutor records the commands and environment it was asked to run
+type EnvRecordingExecutor struct {
+	MockCommandExecutor
+	env  []string
+	args *[][]string
+}
+
+func (m EnvRecordingExecutor) WithEnv(env ...string) CommandExecutor {
+	return EnvRecordingExecutor{env: env, args: m.args}
+}
+
+func (m EnvRecordingExecutor) ExecuteCommand(name string, arg ...string) ([]byte, error) {
+	*m.args = append(*m.args, append(append([]string{name}, arg...), m.env...))
+	return []byte("ok"), nil
+}
+
+func TestCloneReposWithCredentials(t *testing.T) {
+	var args [][]string
+	executor, err := NewCredentialExecutor(EnvRecordingExecutor{args: &args}, StaticToken("hunter2"), "octocat", "github.com")
+	if err != nil {
+		t.Fatalf("NewCredentialExecutor() error = %v", err)
+	}
+	defer executor.Close()
+
+	dir := t.TempDir()
+	jsonStr := `[{"name": "repo1", "html_url": "https://github.com/shimman-dev/repo1"}]`
+	if err := CloneReposFromJson(executor, nil, jsonStr, dir, 1); err != nil {
+		t.Fatalf("CloneReposFromJson() error = %v", err)
+	}
+
+	if len(args) != 1 {
+		t.Fatalf("Expected one clone, got %v", args)
+	}
+	cmd := strings.Join(args[0], " ")
+	if !strings.HasPrefix(cmd, "git clone --progress https://github.com/shimman-dev/repo1 ") {
+		t.Errorf("Expected a clone of the plain URL, got %s", cmd)
+	}
+	if !strings.Contains(cmd, "PISCATOR_GIT_USERNAME=octocat") || !strings.Contains(cmd, "PISCATOR_GIT_PASSWORD=hunter2") {
+		t.Errorf("Expected the credentials in the environment, got %s", cmd)
+	}
+	if strings.Count(cmd, "hunter2") != 1 {
+		t.Errorf("Expected the password only in the environment, got %s", cmd)
+	}
+}
+
+func TestNewCredentialExecutorRequiresEnv(t *testing.T) {
+	if _, err := NewCredentialExecutor(&MockCommandExecutor{}, StaticToken("token"), "x-access-token", ""); err == nil {
+		t.Errorf("Expected an error but did not get one")
+	}
+}
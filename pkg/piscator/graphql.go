@@ -0,0 +1,252 @@
+package piscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// repos fetched per GraphQL page, the most GitHub allows
+const graphQLPageSize = 100
+
+const graphQLRepoFields = `
+	pageInfo { hasNextPage endCursor }
+	nodes {
+		databaseId
+		name
+		url
+		sshUrl
+		isFork
+		isPrivate
+		isArchived
+		visibility
+		diskUsage
+		pushedAt
+		primaryLanguage { name }
+		languages(first: 20, orderBy: {field: SIZE, direction: DESC}) { nodes { name } }
+		repositoryTopics(first: 20) { nodes { topic { name } } }
+		defaultBranchRef { name }
+		licenseInfo { key name spdxId }
+	}`
+
+type graphQLRepo struct {
+	DatabaseID      int64  `json:"databaseId"`
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	SSHURL          string `json:"sshUrl"`
+	IsFork          bool   `json:"isFork"`
+	IsPrivate       bool   `json:"isPrivate"`
+	IsArchived      bool   `json:"isArchived"`
+	Visibility      string `json:"visibility"`
+	DiskUsage       uint   `json:"diskUsage"`
+	PushedAt        string `json:"pushedAt"`
+	PrimaryLanguage *struct {
+		Name string `json:"name"`
+	} `json:"primaryLanguage"`
+	Languages struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"languages"`
+	RepositoryTopics struct {
+		Nodes []struct {
+			Topic struct {
+				Name string `json:"name"`
+			} `json:"topic"`
+		} `json:"nodes"`
+	} `json:"repositoryTopics"`
+	DefaultBranchRef *struct {
+		Name string `json:"name"`
+	} `json:"defaultBranchRef"`
+	LicenseInfo *struct {
+		Key    string `json:"key"`
+		Name   string `json:"name"`
+		SPDXID string `json:"spdxId"`
+	} `json:"licenseInfo"`
+}
+
+type graphQLConnection struct {
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	} `json:"pageInfo"`
+	Nodes []graphQLRepo `json:"nodes"`
+}
+
+type graphQLOwner struct {
+	Repositories graphQLConnection `json:"repositories"`
+}
+
+type graphQLResponse struct {
+	Data struct {
+		Organization *graphQLOwner `json:"organization"`
+		User         *graphQLOwner `json:"user"`
+		Viewer       *graphQLOwner `json:"viewer"`
+	} `json:"data"`
+	Errors []struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// GraphQL reports its rate limit as an error in a 200 response, which the
+// RetryClient can't see
+func (r graphQLResponse) rateLimited() bool {
+	for _, e := range r.Errors {
+		if e.Type == "RATE_LIMITED" {
+			return true
+		}
+	}
+	return false
+}
+
+// Retrieves repositories of a user/organization/self through GitHub's GraphQL
+// API, fetching topics, languages and the default branch in the same query
+// rather than a call per repo. Produces the same output as GetRepos.
+//
+// GraphQL always requires a token, and doesn't support ListOptions yet.
+func GetReposGraphQL(client HttpClient, sleeper Sleeper, observer Observer, name, token, enterpriseHost string, isSelf, isOrg, isForked bool, opts ListOptions) (string, error) {
+	if token == "" {
+		return "", errors.New("the GraphQL API requires a token")
+	}
+	if !opts.isZero() {
+		return "", errors.New("--team, --affiliation, --visibility and --type need the REST API")
+	}
+
+	endpoint := GraphQLURL(enterpriseHost)
+
+	query := buildGraphQLQuery(isSelf, isOrg, isForked)
+	rc := NewRetryClient(client, sleeper, observer)
+
+	var repos []RepoModel
+	var cursor *string
+	for page, attempt := 1, 1; ; page++ {
+		variables := map[string]any{"cursor": cursor}
+		if !isSelf {
+			variables["login"] = name
+		}
+		payload, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+		if err != nil {
+			return "", err
+		}
+
+		req, err := http.NewRequest("POST", endpoint, bytes.NewReader(payload))
+		if err != nil {
+			return "", fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("User-Agent", "shimman-dev/piscator")
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := rc.Do(req)
+		if err != nil {
+			return "", err
+		}
+		var body graphQLResponse
+		err = json.NewDecoder(res.Body).Decode(&body)
+		res.Body.Close()
+		if err != nil {
+			return "", err
+		}
+
+		if body.rateLimited() && attempt < rc.MaxAttempts {
+			// hold the budget like a rate limited REST response, until the reset
+			// GitHub reported or else for a backoff, and ask for the page again
+			wait := rc.Budget.held(time.Now())
+			if wait == 0 {
+				wait = rc.delay(attempt)
+			}
+			rc.Budget.holdUntil(time.Now().Add(wait))
+			attempt++
+			page--
+			continue
+		}
+		attempt = 1
+
+		if len(body.Errors) > 0 {
+			messages := make([]string, 0, len(body.Errors))
+			for _, e := range body.Errors {
+				messages = append(messages, e.Message)
+			}
+			return "", fmt.Errorf("GraphQL error: %s", strings.Join(messages, "; "))
+		}
+
+		owner := body.Data.Organization
+		switch {
+		case isSelf:
+			owner = body.Data.Viewer
+		case !isOrg:
+			owner = body.Data.User
+		}
+		if owner == nil {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+
+		conn := owner.Repositories
+		for _, node := range conn.Nodes {
+			repos = append(repos, node.toRepoModel())
+		}
+		notify(observer, ListPageFetched{URL: endpoint, Page: page, Count: len(conn.Nodes)})
+
+		if !conn.PageInfo.HasNextPage {
+			break
+		}
+		next := conn.PageInfo.EndCursor
+		cursor = &next
+	}
+
+	return finishRepos(repos, isForked)
+}
+
+func buildGraphQLQuery(isSelf, isOrg, isForked bool) string {
+	args := fmt.Sprintf("first: %d, after: $cursor", graphQLPageSize)
+	if !isForked {
+		args += ", isFork: false"
+	}
+
+	switch {
+	case isSelf:
+		// the affiliations REST's /user/repos lists by default
+		return fmt.Sprintf("query($cursor: String) { viewer { repositories(%s, ownerAffiliations: [OWNER, COLLABORATOR, ORGANIZATION_MEMBER]) { %s } } }", args, graphQLRepoFields)
+	case isOrg:
+		return fmt.Sprintf("query($login: String!, $cursor: String) { organization(login: $login) { repositories(%s) { %s } } }", args, graphQLRepoFields)
+	default:
+		return fmt.Sprintf("query($login: String!, $cursor: String) { user(login: $login) { repositories(%s, ownerAffiliations: OWNER) { %s } } }", args, graphQLRepoFields)
+	}
+}
+
+// Maps a GraphQL repository onto the REST shaped RepoModel
+func (r graphQLRepo) toRepoModel() RepoModel {
+	repo := RepoModel{
+		Repo:       Repo{Name: r.Name, URL: r.URL},
+		ID:         r.DatabaseID,
+		Fork:       r.IsFork,
+		Private:    r.IsPrivate,
+		Visibility: strings.ToLower(r.Visibility),
+		Archived:   r.IsArchived,
+		Size:       r.DiskUsage,
+		PushedAt:   r.PushedAt,
+		SSHURL:     r.SSHURL,
+		CloneURL:   r.URL + ".git",
+	}
+	if r.PrimaryLanguage != nil {
+		repo.Lang = r.PrimaryLanguage.Name
+	}
+	for _, l := range r.Languages.Nodes {
+		repo.Languages = append(repo.Languages, l.Name)
+	}
+	for _, t := range r.RepositoryTopics.Nodes {
+		repo.Topics = append(repo.Topics, t.Topic.Name)
+	}
+	if r.DefaultBranchRef != nil {
+		repo.DefaultBranch = r.DefaultBranchRef.Name
+	}
+	if r.LicenseInfo != nil {
+		repo.License = &License{Key: r.LicenseInfo.Key, Name: r.LicenseInfo.Name, SPDXID: r.LicenseInfo.SPDXID}
+	}
+	return repo
+}
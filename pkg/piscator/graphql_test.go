@@ -0,0 +1,176 @@
+package piscator
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const graphQLPage1 = `{"data": {"organization": {"repositories": {
+	"pageInfo": {"hasNextPage": true, "endCursor": "Y3Vyc29yOjE="},
+	"nodes": [{
+		"databaseId": 1,
+		"name": "piscator",
+		"url": "https://github.com/shimman-dev/piscator",
+		"sshUrl": "git@github.com:shimman-dev/piscator.git",
+		"isFork": false,
+		"isPrivate": false,
+		"isArchived": false,
+		"visibility": "PUBLIC",
+		"diskUsage": 1006,
+		"pushedAt": "2023-07-01T12:00:00Z",
+		"primaryLanguage": {"name": "Go"},
+		"languages": {"nodes": [{"name": "Go"}, {"name": "Makefile"}]},
+		"repositoryTopics": {"nodes": [{"topic": {"name": "cli"}}]},
+		"defaultBranchRef": {"name": "main"},
+		"licenseInfo": {"key": "gpl-3.0", "name": "GNU General Public License v3.0", "spdxId": "GPL-3.0"}
+	}]
+}}}}`
+
+const graphQLPage2 = `{"data": {"organization": {"repositories": {
+	"pageInfo": {"hasNextPage": false, "endCursor": null},
+	"nodes": [{
+		"databaseId": 2,
+		"name": "knockerupper",
+		"url": "https://github.com/shimman-dev/knockerupper",
+		"isPrivate": true,
+		"visibility": "PRIVATE",
+		"diskUsage": 14,
+		"primaryLanguage": null,
+		"languages": {"nodes": []},
+		"repositoryTopics": {"nodes": []},
+		"defaultBranchRef": null,
+		"licenseInfo": null
+	}]
+}}}}`
+
+func TestGetReposGraphQL(t *testing.T) {
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, body: graphQLPage1},
+		{status: 200, body: graphQLPage2},
+	}}
+
+	res, err := GetReposGraphQL(client, &MockSleeper{}, nil, "shimman-dev", "token", "", false, true, false, ListOptions{})
+	if err != nil {
+		t.Fatalf("GetReposGraphQL() error = %v", err)
+	}
+
+	var repos []RepoModel
+	if err := json.Unmarshal([]byte(res), &repos); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	expected := []RepoModel{
+		{
+			Repo:          Repo{Name: "piscator", URL: "https://github.com/shimman-dev/piscator"},
+			ID:            1,
+			Lang:          "Go",
+			Visibility:    "public",
+			Size:          1006,
+			PushedAt:      "2023-07-01T12:00:00Z",
+			License:       &License{Key: "gpl-3.0", Name: "GNU General Public License v3.0", SPDXID: "GPL-3.0"},
+			SSHURL:        "git@github.com:shimman-dev/piscator.git",
+			CloneURL:      "https://github.com/shimman-dev/piscator.git",
+			DefaultBranch: "main",
+			Topics:        []string{"cli"},
+			Languages:     []string{"Go", "Makefile"},
+		},
+		{
+			Repo:       Repo{Name: "knockerupper", URL: "https://github.com/shimman-dev/knockerupper"},
+			ID:         2,
+			Private:    true,
+			Visibility: "private",
+			Size:       14,
+			CloneURL:   "https://github.com/shimman-dev/knockerupper.git",
+		},
+	}
+	if !reflect.DeepEqual(repos, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, repos)
+	}
+
+	// the second page asks for the cursor returned by the first
+	body, _ := io.ReadAll(client.requests[1].Body)
+	if !strings.Contains(string(body), `"cursor":"Y3Vyc29yOjE="`) {
+		t.Errorf("Expected the end cursor to be sent, got %s", body)
+	}
+	if !strings.Contains(string(body), "organization(login: $login)") || !strings.Contains(string(body), "isFork: false") {
+		t.Errorf("Expected an organization query without forks, got %s", body)
+	}
+}
+
+func TestGetReposGraphQLErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		responses []mockResponse
+		wantErr   error
+	}{
+		{"no token", "", []mockResponse{{status: 200, body: graphQLPage2}}, nil},
+		{"graphql errors", "token", []mockResponse{{status: 200, body: `{"errors": [{"message": "Something went wrong"}]}`}}, nil},
+		{"unknown owner", "token", []mockResponse{{status: 200, body: `{"data": {"organization": null}}`}}, ErrNotFound},
+		{"unauthorized", "token", []mockResponse{{status: 401, body: `{"message": "Bad credentials"}`}}, ErrUnauthorized},
+		{"invalid json", "token", []mockResponse{{status: 200, body: `{[}`}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &SequenceHttpClient{responses: tt.responses}
+			_, err := GetReposGraphQL(client, &MockSleeper{}, nil, "shimman-dev", tt.token, "", false, true, false, ListOptions{})
+			if err == nil {
+				t.Fatalf("Expected an error but did not get one")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+const graphQLRateLimited = `{"errors": [{"type": "RATE_LIMITED", "message": "API rate limit exceeded for user ID 1."}]}`
+
+func TestGetReposGraphQLRateLimited(t *testing.T) {
+	reset := strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, body: graphQLRateLimited, headers: map[string]string{"X-Ratelimit-Remaining": "0", "X-Ratelimit-Reset": reset}},
+		{status: 200, body: graphQLPage2},
+	}}
+	sleeper := &MockSleeper{}
+
+	res, err := GetReposGraphQL(client, sleeper, nil, "shimman-dev", "token", "", false, true, false, ListOptions{})
+	if err != nil {
+		t.Fatalf("GetReposGraphQL() error = %v", err)
+	}
+	if !strings.Contains(res, "knockerupper") {
+		t.Errorf("Expected the page to be fetched again, got %s", res)
+	}
+	if len(sleeper.Durations) != 1 || sleeper.Durations[0] < 50*time.Second {
+		t.Errorf("Expected to wait for the rate limit reset, slept %v", sleeper.Durations)
+	}
+
+	// a limit that never lifts gives up like the RetryClient does
+	client = &SequenceHttpClient{responses: []mockResponse{{status: 200, body: graphQLRateLimited}}}
+	if _, err := GetReposGraphQL(client, &MockSleeper{}, nil, "shimman-dev", "token", "", false, true, false, ListOptions{}); err == nil || !strings.Contains(err.Error(), "rate limit") {
+		t.Errorf("Expected a rate limit error, got %v", err)
+	}
+	if len(client.requests) != DefaultMaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", DefaultMaxAttempts, len(client.requests))
+	}
+}
+
+func TestBuildGraphQLQuery(t *testing.T) {
+	if q := buildGraphQLQuery(true, false, true); !strings.Contains(q, "viewer {") || strings.Contains(q, "isFork: false") {
+		t.Errorf("Expected a viewer query including forks, got %s", q)
+	}
+	// the same repos REST's /user/repos lists for --self
+	if q := buildGraphQLQuery(true, false, false); !strings.Contains(q, "ownerAffiliations: [OWNER, COLLABORATOR, ORGANIZATION_MEMBER]") {
+		t.Errorf("Expected a viewer query for every affiliation, got %s", q)
+	}
+	if q := buildGraphQLQuery(false, false, false); !strings.Contains(q, "user(login: $login)") || !strings.Contains(q, "ownerAffiliations: OWNER") {
+		t.Errorf("Expected a user query, got %s", q)
+	}
+}
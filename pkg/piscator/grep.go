@@ -0,0 +1,341 @@
+package piscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GrepOptions says what GrepRepos searches for. Patterns are extended regular
+// expressions unless FixedStrings.
+type GrepOptions struct {
+	Pattern          string
+	IgnoreCase       bool
+	FixedStrings     bool
+	FilesWithMatches bool
+	// Builtin searches the working tree with Go's regexp instead of running
+	// git grep, for hosts without git. Untracked files are searched too.
+	Builtin bool
+}
+
+// GrepMatch is a matching line, or a matching file with FilesWithMatches
+type GrepMatch struct {
+	File string `json:"file"`
+	Line int    `json:"line,omitempty"`
+	Text string `json:"text,omitempty"`
+}
+
+// GrepResult holds the matches found in one repo
+type GrepResult struct {
+	Repo    string      `json:"repo"`
+	Count   int         `json:"count"`
+	Matches []GrepMatch `json:"matches"`
+	Err     error       `json:"-"`
+}
+
+// Searches every repo under dir concurrently, repos being directories relative
+// to dir such as FindRepos returns. Results are in the order of repos. The
+// pattern is checked first so an invalid one fails once rather than per repo.
+func GrepRepos(executor CommandExecutor, dir string, repos []string, opts GrepOptions, concurrentLimit int) ([]GrepResult, error) {
+	re, err := grepRegexp(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]GrepResult, len(repos))
+	sem := make(chan struct{}, concurrentLimit)
+	var wg sync.WaitGroup
+	for i, repo := range repos {
+		wg.Add(1)
+		go func(i int, repo string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			repoPath := filepath.Join(dir, filepath.FromSlash(repo))
+			var matches []GrepMatch
+			var err error
+			if opts.Builtin {
+				matches, err = matchFiles(repoPath, re, opts.FilesWithMatches)
+			} else {
+				matches, err = gitGrep(executor, repoPath, opts)
+			}
+			results[i] = GrepResult{Repo: repo, Count: len(matches), Matches: matches, Err: err}
+		}(i, repo)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// Compiles the pattern the way git grep -E or -F reads it
+func grepRegexp(opts GrepOptions) (*regexp.Regexp, error) {
+	pattern := opts.Pattern
+	if opts.FixedStrings {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+func gitGrep(executor CommandExecutor, repoPath string, opts GrepOptions) ([]GrepMatch, error) {
+	// -z separates the file, line and text with NULs so any file name parses
+	arg := []string{"grep", "-I", "-z", "-n", "-E"}
+	if opts.FixedStrings {
+		arg[len(arg)-1] = "-F"
+	}
+	if opts.IgnoreCase {
+		arg = append(arg, "-i")
+	}
+	if opts.FilesWithMatches {
+		arg = append(arg, "-l")
+	}
+	arg = append(arg, "-e", opts.Pattern)
+
+	out, err := executor.ExecuteCommandInDir(repoPath, "git", arg...)
+	if err != nil {
+		// git grep exits 1 without output when nothing matches
+		if len(bytes.TrimSpace(out)) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("git grep: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return parseGitGrep(out, opts.FilesWithMatches), nil
+}
+
+// Parses git grep -z -n output, file\0line\0text per match, or file\0 with -l.
+// Anything else git printed, such as warnings, is skipped.
+func parseGitGrep(out []byte, filesWithMatches bool) []GrepMatch {
+	var matches []GrepMatch
+	if filesWithMatches {
+		for _, file := range strings.Split(string(out), "\x00") {
+			if file = strings.TrimPrefix(file, "\n"); file != "" {
+				matches = append(matches, GrepMatch{File: file})
+			}
+		}
+		return matches
+	}
+
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		matches = append(matches, GrepMatch{File: fields[0], Line: n, Text: fields[2]})
+	}
+	return matches
+}
+
+// Searches the working tree of a repo, skipping .git and binary files like
+// git grep -I
+func matchFiles(repoPath string, re *regexp.Regexp, filesWithMatches bool) ([]GrepMatch, error) {
+	var matches []GrepMatch
+	err := filepath.WalkDir(repoPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		// git treats a file with a NUL in its first 8000 bytes as binary
+		if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(repoPath, p)
+		if err != nil {
+			return err
+		}
+		file := filepath.ToSlash(rel)
+
+		for i, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if filesWithMatches {
+				matches = append(matches, GrepMatch{File: file})
+				break
+			}
+			matches = append(matches, GrepMatch{File: file, Line: i + 1, Text: strings.TrimSuffix(line, "\r")})
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// Writes the results grouped by repo, each headed by its count, followed by
+// the totals. Repos without matches are left out.
+func WriteGrepResults(w io.Writer, results []GrepResult, filesWithMatches bool) error {
+	unit := func(n int) string {
+		switch {
+		case filesWithMatches && n == 1:
+			return "1 file"
+		case filesWithMatches:
+			return fmt.Sprintf("%d files", n)
+		case n == 1:
+			return "1 match"
+		default:
+			return fmt.Sprintf("%d matches", n)
+		}
+	}
+
+	var total, repos, failed int
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			if _, err := fmt.Fprintf(w, "%s: %s\n\n", result.Repo, result.Err); err != nil {
+				return err
+			}
+			continue
+		}
+		if result.Count == 0 {
+			continue
+		}
+		total += result.Count
+		repos++
+
+		if _, err := fmt.Fprintf(w, "%s (%s)\n", result.Repo, unit(result.Count)); err != nil {
+			return err
+		}
+		for _, match := range result.Matches {
+			var err error
+			if filesWithMatches {
+				_, err = fmt.Fprintf(w, "  %s\n", match.File)
+			} else {
+				_, err = fmt.Fprintf(w, "  %s:%d: %s\n", match.File, match.Line, match.Text)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	summary := fmt.Sprintf("%s in %d of %d repos", unit(total), repos, len(results))
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d failed", failed)
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
+
+// Writes the results as a JSON array with an error key for repos that
+// couldn't be searched
+func WriteGrepJSON(w io.Writer, results []GrepResult) error {
+	type grepResultJSON struct {
+		GrepResult
+		Matches []GrepMatch `json:"matches"`
+		Error   string      `json:"error,omitempty"`
+	}
+
+	out := make([]grepResultJSON, len(results))
+	for i, result := range results {
+		out[i] = grepResultJSON{GrepResult: result, Matches: result.Matches}
+		if out[i].Matches == nil {
+			out[i].Matches = []GrepMatch{}
+		}
+		if result.Err != nil {
+			out[i].Error = result.Err.Error()
+		}
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// Keeps the repos matching every filter, each a field=value pair compared
+// case-insensitively with the field as cast prints it. A list field such as
+// topics matches when any of its items does, and an empty value matches repos
+// without the field.
+func FilterRepos(repos []RepoModel, filters []string) ([]RepoModel, error) {
+	type filter struct{ field, value string }
+	parsed := make([]filter, len(filters))
+	for i, f := range filters {
+		field, value, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter %q, expected field=value", f)
+		}
+		fields, err := resolveFields([]string{field})
+		if err != nil {
+			return nil, err
+		}
+		parsed[i] = filter{fields[0], strings.TrimSpace(value)}
+	}
+
+	var kept []RepoModel
+	for _, repo := range repos {
+		record, err := newRepoRecord(repo, nil)
+		if err != nil {
+			return nil, err
+		}
+		keep := true
+		for _, f := range parsed {
+			keep = keep && filterMatches(record.cell(f.field), f.value)
+		}
+		if keep {
+			kept = append(kept, repo)
+		}
+	}
+	return kept, nil
+}
+
+func filterMatches(cell, value string) bool {
+	if value == "" {
+		return cell == ""
+	}
+	for _, item := range strings.Split(cell, ";") {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Keeps the repos found on disk that are in a listing, such as a filtered
+// repos.json, matching them by the directory reel clones each into
+func ListedRepos(found []string, listed []RepoModel) []string {
+	dirs := map[string]bool{}
+	for _, repo := range listed {
+		repoPath, _ := cloneTarget("", repo.Repo)
+		dirs[repoPath] = true
+	}
+	var kept []string
+	for _, repo := range found {
+		if dirs[repo] {
+			kept = append(kept, repo)
+		}
+	}
+	return kept
+}
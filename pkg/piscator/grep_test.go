@@ -0,0 +1,242 @@
+package piscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// GrepExecutor answers git grep with the output of each repo, recording the
+// arguments it was run with
+type GrepExecutor struct {
+	MockCommandExecutor
+	out  map[string]string
+	errs map[string]error
+	args *[]string
+}
+
+func (m GrepExecutor) ExecuteCommandInDir(dir, name string, arg ...string) ([]byte, error) {
+	*m.args = append(*m.args, strings.Join(arg, " "))
+	for repo, out := range m.out {
+		if strings.HasSuffix(dir, repo) {
+			return []byte(out), m.errs[repo]
+		}
+	}
+	return nil, errors.New("exit status 1")
+}
+
+func TestGrepReposGit(t *testing.T) {
+	var args []string
+	executor := GrepExecutor{
+		out: map[string]string{
+			"api": "main.go\x0012\x00\t// TODO: retry\nREADME.md\x003\x00TODO list\n",
+			"lib": "fatal: not a git repository",
+		},
+		errs: map[string]error{"lib": errors.New("exit status 128")},
+		args: &args,
+	}
+
+	results, err := GrepRepos(executor, "fleet", []string{"api", "lib", "web"}, GrepOptions{Pattern: "TODO", IgnoreCase: true}, 1)
+	if err != nil {
+		t.Fatalf("GrepRepos() error = %v", err)
+	}
+
+	want := []GrepMatch{{File: "main.go", Line: 12, Text: "\t// TODO: retry"}, {File: "README.md", Line: 3, Text: "TODO list"}}
+	if results[0].Count != 2 || !reflect.DeepEqual(results[0].Matches, want) {
+		t.Errorf("Expected %v, got %v", want, results[0].Matches)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "not a git repository") {
+		t.Errorf("Expected lib to fail, got %v", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Count != 0 {
+		t.Errorf("Expected no matches in web, got %v", results[2])
+	}
+	if args[0] != "grep -I -z -n -E -i -e TODO" {
+		t.Errorf("Expected git grep -I -z -n -E -i -e TODO, got %s", args[0])
+	}
+}
+
+func TestParseGitGrepFilesWithMatches(t *testing.T) {
+	got := parseGitGrep([]byte("main.go\x00dir/with space.go\x00"), true)
+	want := []GrepMatch{{File: "main.go"}, {File: "dir/with space.go"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestGrepReposBuiltin(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"api/.git/HEAD":      "TODO in git's own files\n",
+		"api/main.go":        "package main\n\n// todo: retry\nfunc main() {}\n",
+		"api/docs/notes.txt": "a.b TODO\r\nnothing\nTODO again",
+		"api/logo.png":       "TODO\x00binary",
+		"web/index.html":     "<html></html>\n",
+	})
+
+	tests := []struct {
+		name string
+		opts GrepOptions
+		want []GrepMatch
+	}{
+		{
+			name: "regexp",
+			opts: GrepOptions{Pattern: "^TODO"},
+			want: []GrepMatch{{File: "docs/notes.txt", Line: 3, Text: "TODO again"}},
+		},
+		{
+			name: "ignore case",
+			opts: GrepOptions{Pattern: "todo", IgnoreCase: true},
+			want: []GrepMatch{
+				{File: "docs/notes.txt", Line: 1, Text: "a.b TODO"},
+				{File: "docs/notes.txt", Line: 3, Text: "TODO again"},
+				{File: "main.go", Line: 3, Text: "// todo: retry"},
+			},
+		},
+		{
+			name: "fixed strings",
+			opts: GrepOptions{Pattern: "a.b", FixedStrings: true},
+			want: []GrepMatch{{File: "docs/notes.txt", Line: 1, Text: "a.b TODO"}},
+		},
+		{
+			name: "files with matches",
+			opts: GrepOptions{Pattern: "TODO", IgnoreCase: true, FilesWithMatches: true},
+			want: []GrepMatch{{File: "docs/notes.txt"}, {File: "main.go"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.opts.Builtin = true
+			results, err := GrepRepos(nil, root, []string{"api", "web"}, test.opts, 2)
+			if err != nil {
+				t.Fatalf("GrepRepos() error = %v", err)
+			}
+			if !reflect.DeepEqual(results[0].Matches, test.want) {
+				t.Errorf("Expected %v, got %v", test.want, results[0].Matches)
+			}
+			if results[0].Count != len(test.want) {
+				t.Errorf("Expected count %d, got %d", len(test.want), results[0].Count)
+			}
+			if results[1].Count != 0 || results[1].Err != nil {
+				t.Errorf("Expected no matches in web, got %v", results[1])
+			}
+		})
+	}
+}
+
+func TestGrepReposInvalidPattern(t *testing.T) {
+	if _, err := GrepRepos(nil, t.TempDir(), []string{"api"}, GrepOptions{Pattern: "TO(DO"}, 1); err == nil {
+		t.Errorf("Expected an invalid pattern to fail")
+	}
+}
+
+func TestWriteGrepResults(t *testing.T) {
+	results := []GrepResult{
+		{Repo: "api", Count: 2, Matches: []GrepMatch{{File: "main.go", Line: 3, Text: "// TODO"}, {File: "x.go", Line: 1, Text: "TODO"}}},
+		{Repo: "lib", Err: errors.New("git grep: exit status 128")},
+		{Repo: "web"},
+		{Repo: "cli", Count: 1, Matches: []GrepMatch{{File: "cmd.go", Line: 9, Text: "TODO"}}},
+	}
+
+	var b bytes.Buffer
+	if err := WriteGrepResults(&b, results, false); err != nil {
+		t.Fatalf("WriteGrepResults() error = %v", err)
+	}
+	want := `api (2 matches)
+  main.go:3: // TODO
+  x.go:1: TODO
+
+lib: git grep: exit status 128
+
+cli (1 match)
+  cmd.go:9: TODO
+
+3 matches in 2 of 4 repos, 1 failed
+`
+	if b.String() != want {
+		t.Errorf("Expected %q, got %q", want, b.String())
+	}
+
+	b.Reset()
+	files := []GrepResult{{Repo: "api", Count: 1, Matches: []GrepMatch{{File: "main.go"}}}}
+	if err := WriteGrepResults(&b, files, true); err != nil {
+		t.Fatalf("WriteGrepResults() error = %v", err)
+	}
+	if want := "api (1 file)\n  main.go\n\n1 file in 1 of 1 repos\n"; b.String() != want {
+		t.Errorf("Expected %q, got %q", want, b.String())
+	}
+}
+
+func TestWriteGrepJSON(t *testing.T) {
+	results := []GrepResult{
+		{Repo: "api", Count: 1, Matches: []GrepMatch{{File: "main.go", Line: 3, Text: "// TODO"}}},
+		{Repo: "lib", Err: errors.New("git grep: exit status 128")},
+	}
+
+	var b bytes.Buffer
+	if err := WriteGrepJSON(&b, results); err != nil {
+		t.Fatalf("WriteGrepJSON() error = %v", err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("Expected JSON, got %q", b.String())
+	}
+	want := []map[string]any{
+		{"repo": "api", "count": float64(1), "matches": []any{map[string]any{"file": "main.go", "line": float64(3), "text": "// TODO"}}},
+		{"repo": "lib", "count": float64(0), "matches": []any{}, "error": "git grep: exit status 128"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestFilterRepos(t *testing.T) {
+	repos := []RepoModel{
+		{Repo: Repo{Name: "api"}, Visibility: "private", Topics: []string{"go", "cli"}},
+		{Repo: Repo{Name: "web"}, Visibility: "public", Description: "site"},
+		{Repo: Repo{Name: "lib"}, Visibility: "public", Topics: []string{"go"}},
+	}
+
+	tests := []struct {
+		filters []string
+		want    []string
+		wantErr bool
+	}{
+		{filters: nil, want: []string{"api", "web", "lib"}},
+		{filters: []string{"visibility=PUBLIC"}, want: []string{"web", "lib"}},
+		{filters: []string{"topics=cli"}, want: []string{"api"}},
+		{filters: []string{"topics=go", "visibility=public"}, want: []string{"lib"}},
+		{filters: []string{"description="}, want: []string{"api", "lib"}},
+		{filters: []string{"visibility"}, wantErr: true},
+		{filters: []string{"stars=5"}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		kept, err := FilterRepos(repos, test.filters)
+		if (err != nil) != test.wantErr {
+			t.Errorf("FilterRepos(%v) error = %v", test.filters, err)
+			continue
+		}
+		var names []string
+		for _, repo := range kept {
+			names = append(names, repo.Name)
+		}
+		if !reflect.DeepEqual(names, test.want) {
+			t.Errorf("Expected %v, got %v", test.want, names)
+		}
+	}
+}
+
+func TestListedRepos(t *testing.T) {
+	listed := []RepoModel{
+		{Repo: Repo{Name: "api"}},
+		{Repo: Repo{Name: "aa11", Gist: true}},
+	}
+	got := ListedRepos([]string{"api", "gists/aa11", "web"}, listed)
+	if want := []string{"api", "gists/aa11"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
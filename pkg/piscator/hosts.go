@@ -0,0 +1,116 @@
+package piscator
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// the host used when no GitHub Enterprise Server host is given
+const defaultHost = "github.com"
+
+// Reduces a host given as a URL such as https://ghe.example.com/ to the bare
+// host, an empty host is github.com
+func NormalizeHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host = strings.TrimSuffix(host, "/")
+	if host == "" || host == "api.github.com" {
+		return defaultHost
+	}
+	return host
+}
+
+// Returns the web root of a forge host, where OAuth and clone URLs live
+func WebURL(host string) string {
+	return "https://" + NormalizeHost(host)
+}
+
+// Returns the REST API root of a forge host, GitHub Enterprise Server serves
+// it under /api/v3 instead of a separate api. host
+func APIBaseURL(host string) string {
+	host = NormalizeHost(host)
+	if host == defaultHost {
+		return "https://api.github.com"
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// Returns the GraphQL endpoint of a forge host
+func GraphQLURL(host string) string {
+	host = NormalizeHost(host)
+	if host == defaultHost {
+		return "https://api.github.com/graphql"
+	}
+	return "https://" + host + "/api/graphql"
+}
+
+// TLSConfig holds the certificates needed to talk to a GitHub Enterprise
+// Server behind a private CA or requiring client certificates
+type TLSConfig struct {
+	// CAFile is a PEM bundle trusted in addition to the system roots
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and its key
+	CertFile string
+	KeyFile  string
+}
+
+// Checks if any certificate was configured
+func (c TLSConfig) IsZero() bool {
+	return c == TLSConfig{}
+}
+
+// Returns an http.Client using the configured certificates
+func (c TLSConfig) Client() (*http.Client, error) {
+	if c.IsZero() {
+		return http.DefaultClient, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if c.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", c.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, errors.New("a client certificate needs both the certificate and its key")
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport}, nil
+}
+
+// Returns the environment that makes git use the same certificates. git
+// replaces its CA bundle with GIT_SSL_CAINFO rather than adding to it.
+func (c TLSConfig) GitEnv() []string {
+	var env []string
+	if c.CAFile != "" {
+		env = append(env, "GIT_SSL_CAINFO="+c.CAFile)
+	}
+	if c.CertFile != "" {
+		env = append(env, "GIT_SSL_CERT="+c.CertFile, "GIT_SSL_KEY="+c.KeyFile)
+	}
+	return env
+}
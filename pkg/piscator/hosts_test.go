@@ -0,0 +1,87 @@
+package piscator
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHostURLs(t *testing.T) {
+	tests := []struct {
+		host    string
+		web     string
+		api     string
+		graphql string
+	}{
+		{"", "https://github.com", "https://api.github.com", "https://api.github.com/graphql"},
+		{"github.com", "https://github.com", "https://api.github.com", "https://api.github.com/graphql"},
+		{"ghe.example.com", "https://ghe.example.com", "https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql"},
+		{"https://ghe.example.com/", "https://ghe.example.com", "https://ghe.example.com/api/v3", "https://ghe.example.com/api/graphql"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := WebURL(tt.host); got != tt.web {
+				t.Errorf("Expected %s, got %s", tt.web, got)
+			}
+			if got := APIBaseURL(tt.host); got != tt.api {
+				t.Errorf("Expected %s, got %s", tt.api, got)
+			}
+			if got := GraphQLURL(tt.host); got != tt.graphql {
+				t.Errorf("Expected %s, got %s", tt.graphql, got)
+			}
+		})
+	}
+}
+
+func TestTLSConfigClient(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	// the default client doesn't trust the test server's certificate
+	if client, _ := (TLSConfig{}).Client(); client != http.DefaultClient {
+		t.Errorf("Expected the default client without certificates")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0600); err != nil {
+		t.Fatalf("Failed to write CA bundle: %v", err)
+	}
+
+	client, err := TLSConfig{CAFile: caFile}.Client()
+	if err != nil {
+		t.Fatalf("Client() error = %v", err)
+	}
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected the CA bundle to be trusted, got %v", err)
+	}
+	res.Body.Close()
+
+	errorConfigs := []TLSConfig{
+		{CAFile: filepath.Join(t.TempDir(), "missing.pem")},
+		{CAFile: writeTestFile(t, "empty.pem", "not a certificate")},
+		{CertFile: caFile},
+	}
+	for _, config := range errorConfigs {
+		if _, err := config.Client(); err == nil {
+			t.Errorf("Expected an error for %+v", config)
+		}
+	}
+}
+
+func TestTLSConfigGitEnv(t *testing.T) {
+	config := TLSConfig{CAFile: "/etc/ca.pem", CertFile: "/etc/client.pem", KeyFile: "/etc/client.key"}
+	expected := []string{"GIT_SSL_CAINFO=/etc/ca.pem", "GIT_SSL_CERT=/etc/client.pem", "GIT_SSL_KEY=/etc/client.key"}
+	if got := config.GitEnv(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if got := (TLSConfig{}).GitEnv(); got != nil {
+		t.Errorf("Expected no environment, got %v", got)
+	}
+}
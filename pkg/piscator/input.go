@@ -0,0 +1,296 @@
+package piscator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Reads the repos to reel from a list that didn't come from a forge API. The
+// list is any output format cast writes, with or without metadata: JSON,
+// jsonl, YAML, CSV or TSV with name, url and branch columns, an aligned table
+// or a list of git URLs one per line. Blank lines and lines starting with #
+// are skipped in URL lists, tables, CSV and TSV.
+//
+// CSV may start with a header naming its columns in any order, otherwise the
+// columns are name, url and branch. cast's html_url column is read as the url,
+// and its by_owner column clones the repo into an owner/name directory.
+// Missing names are taken from the URL.
+func ReadRepoList(r io.Reader) ([]RepoModel, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []RepoModel
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) == 0:
+		return nil, fmt.Errorf("empty repo list")
+	case trimmed[0] == '[':
+		if err := json.Unmarshal(trimmed, &repos); err != nil {
+			return nil, fmt.Errorf("error reading repo list: %w", err)
+		}
+	case trimmed[0] == '{':
+		if repos, err = readRepoObjects(trimmed); err != nil {
+			return nil, err
+		}
+	case isRepoYAML(trimmed):
+		if repos, err = readRepoYAML(trimmed); err != nil {
+			return nil, err
+		}
+	default:
+		lines := listLines(data)
+		switch {
+		case len(lines) > 0 && strings.Contains(lines[0], "\t"):
+			repos, err = readRepoCSV(strings.Join(lines, "\n"), '\t')
+		case len(lines) > 0 && strings.Contains(lines[0], ","):
+			repos, err = readRepoCSV(strings.Join(lines, "\n"), ',')
+		case len(lines) > 0 && isRepoCSVHeader(strings.Fields(lines[0])):
+			repos, err = readRepoTable(data)
+		default:
+			repos, err = readRepoURLs(lines)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seen := map[string]bool{}
+	for i := range repos {
+		if repos[i].URL == "" {
+			return nil, fmt.Errorf("repo %d has no URL", i+1)
+		}
+		if repos[i].Name == "" {
+			repos[i].Name = RepoNameFromURL(repos[i].URL)
+		}
+		// every repo is cloned into a directory named after it
+		if name := repos[i].Name; name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+			return nil, fmt.Errorf("invalid repo name %q", name)
+		}
+		// repos cloned by owner only collide within the same owner
+		target, _ := cloneTarget("", repos[i].Repo)
+		if seen[target] {
+			return nil, fmt.Errorf("more than one repo is named %q, give them distinct names", repos[i].displayName())
+		}
+		seen[target] = true
+	}
+	return repos, nil
+}
+
+// Reads JSON written with metadata, see WriteReposWithMetadata, or jsonl with
+// a repo on every line
+func readRepoObjects(data []byte) ([]RepoModel, error) {
+	var repos []RepoModel
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		var object json.RawMessage
+		err := dec.Decode(&object)
+		if err == io.EOF {
+			return repos, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading repo list: %w", err)
+		}
+
+		var file struct {
+			Repos *[]RepoModel `json:"repos"`
+		}
+		if err := json.Unmarshal(object, &file); err != nil {
+			return nil, fmt.Errorf("error reading repo list: %w", err)
+		}
+		if file.Repos != nil {
+			repos = append(repos, *file.Repos...)
+			continue
+		}
+		var repo RepoModel
+		if err := json.Unmarshal(object, &repo); err != nil {
+			return nil, fmt.Errorf("error reading repo list: %w", err)
+		}
+		repos = append(repos, repo)
+	}
+}
+
+// cast's YAML is a list of repos, or a mapping of metadata and repos
+func isRepoYAML(data []byte) bool {
+	for _, prefix := range []string{"- ", "metadata:", "repos:"} {
+		if bytes.HasPrefix(data, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func readRepoYAML(data []byte) ([]RepoModel, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("error reading repo list: %w", err)
+	}
+	if file, ok := v.(map[string]any); ok {
+		v = file["repos"]
+	}
+	// the keys are those of cast's JSON, so the repos are read through it
+	js, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error reading repo list: %w", err)
+	}
+	var repos []RepoModel
+	if err := json.Unmarshal(js, &repos); err != nil {
+		return nil, fmt.Errorf("error reading repo list: %w", err)
+	}
+	return repos, nil
+}
+
+// Reads cast's aligned table, splitting every row where the header's columns
+// start. Cells may hold spaces, such as a description, but never line up with
+// a column they don't belong to.
+func readRepoTable(data []byte) ([]RepoModel, error) {
+	var rows [][]rune
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			rows = append(rows, []rune(line))
+		}
+	}
+
+	var starts []int
+	for i, r := range rows[0] {
+		if r != ' ' && (i == 0 || rows[0][i-1] == ' ') {
+			starts = append(starts, i)
+		}
+	}
+
+	records := make([][]string, len(rows))
+	for i, row := range rows {
+		records[i] = make([]string, len(starts))
+		for j, start := range starts {
+			end := len(row)
+			if j+1 < len(starts) {
+				end = min(starts[j+1], len(row))
+			}
+			if start < end {
+				records[i][j] = strings.TrimSpace(string(row[start:end]))
+			}
+		}
+	}
+	return readRepoRecords(records)
+}
+
+// Returns the lines of a list that aren't blank or comments
+func listLines(data []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func readRepoURLs(lines []string) ([]RepoModel, error) {
+	var repos []RepoModel
+	for _, line := range lines {
+		if strings.ContainsAny(line, " \t") {
+			return nil, fmt.Errorf("expected one git URL per line, got %q", line)
+		}
+		repos = append(repos, RepoModel{Repo: Repo{URL: line}})
+	}
+	return repos, nil
+}
+
+func readRepoCSV(text string, comma rune) ([]RepoModel, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("error reading repo list: %w", err)
+	}
+	return readRepoRecords(records)
+}
+
+// Reads the records of CSV, TSV or a table, the first may be a header
+func readRepoRecords(records [][]string) ([]RepoModel, error) {
+	columns := map[string]int{"name": 0, "url": 1, "branch": 2}
+	if isRepoCSVHeader(records[0]) {
+		columns = map[string]int{}
+		for i, field := range records[0] {
+			columns[strings.ToLower(strings.TrimSpace(field))] = i
+		}
+		if _, ok := columns["url"]; !ok {
+			if i, ok := columns["html_url"]; ok {
+				columns["url"] = i
+			}
+		}
+		if _, ok := columns["url"]; !ok {
+			return nil, fmt.Errorf("repo list header has no url column")
+		}
+		records = records[1:]
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var repos []RepoModel
+	for _, record := range records {
+		byOwner, _ := strconv.ParseBool(field(record, "by_owner"))
+		repos = append(repos, RepoModel{Repo: Repo{
+			Name:    field(record, "name"),
+			URL:     field(record, "url"),
+			Branch:  field(record, "branch"),
+			ByOwner: byOwner,
+		}})
+	}
+	return repos, nil
+}
+
+// A header names its columns, a record holds actual names and URLs
+func isRepoCSVHeader(record []string) bool {
+	for _, field := range record {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "name", "url", "html_url", "branch", "by_owner":
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the owner in a git URL, team for git@example.com:team/repo.git or
+// https://example.com/team/repo/
+func RepoOwnerFromURL(rawURL string) string {
+	owner := strings.TrimRight(rawURL, "/")
+	if i := strings.LastIndexAny(owner, "/:"); i >= 0 {
+		owner = owner[:i]
+	}
+	if i := strings.LastIndexAny(owner, "/:"); i >= 0 {
+		owner = owner[i+1:]
+	}
+	return owner
+}
+
+// Returns the name git clone would give the directory of a git URL, such as
+// repo for git@example.com:team/repo.git or https://example.com/team/repo/
+func RepoNameFromURL(rawURL string) string {
+	name := strings.TrimRight(rawURL, "/")
+	name = strings.TrimSuffix(name, ".git")
+	// scp-like URLs separate the host and path with a colon
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
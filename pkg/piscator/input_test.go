@@ -0,0 +1,166 @@
+package piscator
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestReadRepoList(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		want      []Repo
+		wantError bool
+	}{
+		{
+			name:  "cast json",
+			input: `[{"name": "piscator", "html_url": "https://github.com/shimman-dev/piscator", "language": "Go"}]`,
+			want:  []Repo{{Name: "piscator", URL: "https://github.com/shimman-dev/piscator"}},
+		},
+		{
+			name:  "url list",
+			input: "# mirrors\nhttps://git.example.com/team/api.git\n\ngit@git.example.com:team/web.git\nssh://git.example.com/srv/tools/\n",
+			want: []Repo{
+				{Name: "api", URL: "https://git.example.com/team/api.git"},
+				{Name: "web", URL: "git@git.example.com:team/web.git"},
+				{Name: "tools", URL: "ssh://git.example.com/srv/tools/"},
+			},
+		},
+		{
+			name:  "csv without header",
+			input: "api,https://git.example.com/team/api.git,release\n,git@git.example.com:team/web.git\n",
+			want: []Repo{
+				{Name: "api", URL: "https://git.example.com/team/api.git", Branch: "release"},
+				{Name: "web", URL: "git@git.example.com:team/web.git"},
+			},
+		},
+		{
+			name:  "csv with header",
+			input: "url, branch, name\nhttps://git.example.com/team/api.git, main, backend\n",
+			want:  []Repo{{Name: "backend", URL: "https://git.example.com/team/api.git", Branch: "main"}},
+		},
+		{
+			name:  "same names cloned by owner",
+			input: `[{"name": "cobra", "html_url": "https://github.com/spf13/cobra", "by_owner": true}, {"name": "cobra", "html_url": "https://github.com/octocat/cobra", "by_owner": true}]`,
+			want: []Repo{
+				{Name: "cobra", URL: "https://github.com/spf13/cobra", ByOwner: true},
+				{Name: "cobra", URL: "https://github.com/octocat/cobra", ByOwner: true},
+			},
+		},
+		{
+			name:  "csv cloned by owner",
+			input: "name,html_url,by_owner\ncobra,https://github.com/spf13/cobra,true\n",
+			want:  []Repo{{Name: "cobra", URL: "https://github.com/spf13/cobra", ByOwner: true}},
+		},
+		{"csv header without url", "name,branch\napi,main\n", nil, true},
+		{"duplicate names", "https://a.example.com/api.git\nhttps://b.example.com/api.git\n", nil, true},
+		{"escaping name", `[{"name": "..", "html_url": "https://example.com/x.git"}]`, nil, true},
+		{"missing url", "api,,main\n", nil, true},
+		{"two fields on a line", "https://a.example.com/api.git main\n", nil, true},
+		{"empty", "\n\n", nil, true},
+		{"invalid json", `[{"name": }]`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos, err := ReadRepoList(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantError {
+				t.Fatalf("ReadRepoList() error = %v, wantError %v", err, tt.wantError)
+			}
+			var got []Repo
+			for _, repo := range repos {
+				got = append(got, repo.Repo)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRepoNameFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/shimman-dev/piscator":     "piscator",
+		"https://github.com/shimman-dev/piscator.git": "piscator",
+		"git@example.com:team/repo.git":               "repo",
+		"git@example.com:repo.git":                    "repo",
+		"ssh://example.com/srv/repo/":                 "repo",
+		"/srv/git/repo.git":                           "repo",
+	}
+	for url, want := range tests {
+		if got := RepoNameFromURL(url); got != want {
+			t.Errorf("RepoNameFromURL(%q): Expected %s, got %s", url, want, got)
+		}
+	}
+}
+
+func TestRepoOwnerFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/shimman-dev/piscator":  "shimman-dev",
+		"https://github.com/shimman-dev/piscator/": "shimman-dev",
+		"git@example.com:team/repo.git":            "team",
+		"ssh://example.com/srv/repo":               "srv",
+	}
+	for url, want := range tests {
+		if got := RepoOwnerFromURL(url); got != want {
+			t.Errorf("RepoOwnerFromURL(%q): Expected %s, got %s", url, want, got)
+		}
+	}
+}
+
+func TestCloneReposByOwner(t *testing.T) {
+	var args [][]string
+	executor := EnvRecordingExecutor{args: &args}
+
+	dir := t.TempDir()
+	jsonStr := `[{"name": "cobra", "html_url": "https://github.com/spf13/cobra", "by_owner": true}, {"name": "cobra", "html_url": "https://github.com/octocat/cobra", "by_owner": true}]`
+	if err := CloneReposFromJson(executor, nil, jsonStr, dir, 1); err != nil {
+		t.Fatalf("CloneReposFromJson() error = %v", err)
+	}
+
+	var targets []string
+	for _, arg := range args {
+		targets = append(targets, arg[len(arg)-1])
+	}
+	sort.Strings(targets)
+	if want := []string{dir + "/octocat/cobra", dir + "/spf13/cobra"}; !reflect.DeepEqual(targets, want) {
+		t.Errorf("Expected %v, got %v", want, targets)
+	}
+}
+
+func TestCloneReposSSH(t *testing.T) {
+	var args [][]string
+	executor := EnvRecordingExecutor{args: &args}
+
+	dir := t.TempDir()
+	repos, err := ReadRepoList(strings.NewReader("ssh://git.example.com/srv/tools/\n"))
+	if err != nil {
+		t.Fatalf("ReadRepoList() error = %v", err)
+	}
+	jsonStr, _ := json.Marshal(repos)
+	if err := CloneReposFromJson(executor, nil, string(jsonStr), dir, 1); err != nil {
+		t.Fatalf("CloneReposFromJson() error = %v", err)
+	}
+
+	want := [][]string{{"git", "clone", "ssh://git.example.com/srv/tools/", dir + "/tools"}}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected %v, got %v", want, args)
+	}
+}
+
+func TestCloneReposBranch(t *testing.T) {
+	var args [][]string
+	executor := EnvRecordingExecutor{args: &args}
+
+	jsonStr := `[{"name": "api", "html_url": "https://git.example.com/team/api.git", "branch": "release"}]`
+	if err := CloneReposFromJson(executor, nil, jsonStr, t.TempDir(), 1); err != nil {
+		t.Fatalf("CloneReposFromJson() error = %v", err)
+	}
+
+	if len(args) != 1 || !strings.HasPrefix(strings.Join(args[0], " "), "git clone --branch release https://git.example.com/team/api.git ") {
+		t.Errorf("Expected a clone of the release branch, got %v", args)
+	}
+}
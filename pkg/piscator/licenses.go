@@ -0,0 +1,228 @@
+package piscator
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// License audit statuses reported in LicenseReport.Status
+const (
+	LicenseOK        = "ok"
+	LicenseMissing   = "missing"
+	LicenseMismatch  = "mismatch"
+	LicenseNotReeled = "not-reeled"
+)
+
+// NoAssertion is the SPDX identifier GitHub reports for licenses it can't classify
+const NoAssertion = "NOASSERTION"
+
+// LicenseReport is the result of auditing the license of a single repository
+type LicenseReport struct {
+	Name        string `json:"name"`
+	URL         string `json:"html_url"`
+	APILicense  string `json:"api_license"`
+	FileLicense string `json:"file_license"`
+	File        string `json:"file"`
+	Status      string `json:"status"`
+	Allowed     bool   `json:"allowed"`
+}
+
+// licenseFingerprint matches a license when every phrase is present and none
+// of the excluded phrases are. Titled licenses are only matched on their
+// title, since their bodies name other licenses: GPL-3.0 mentions the AGPL in
+// section 13 and MPL-2.0 lists the GPL family as Secondary Licenses.
+type licenseFingerprint struct {
+	spdx     string
+	phrases  []string
+	excludes []string
+	titled   bool
+}
+
+// the title and preamble of a license file are within its first lines
+const licenseTitleLines = 5
+
+// Ordered from most to least specific, the GPL family share a lot of wording
+var licenseFingerprints = []licenseFingerprint{
+	{spdx: "MPL-2.0", phrases: []string{"mozilla public license", "version 2.0"}, titled: true},
+	{spdx: "AGPL-3.0", phrases: []string{"gnu affero general public license", "version 3"}, titled: true},
+	{spdx: "LGPL-3.0", phrases: []string{"gnu lesser general public license", "version 3"}, titled: true},
+	{spdx: "LGPL-2.1", phrases: []string{"gnu lesser general public license", "version 2.1"}, titled: true},
+	{spdx: "GPL-3.0", phrases: []string{"gnu general public license", "version 3"}, titled: true},
+	{spdx: "GPL-2.0", phrases: []string{"gnu general public license", "version 2"}, titled: true},
+	{spdx: "Apache-2.0", phrases: []string{"apache license", "version 2.0"}, titled: true},
+	{spdx: "BSL-1.0", phrases: []string{"boost software license - version 1.0"}, titled: true},
+	{spdx: "Unlicense", phrases: []string{"this is free and unencumbered software released into the public domain"}, titled: true},
+	{spdx: "CC0-1.0", phrases: []string{"cc0 1.0 universal"}, titled: true},
+	{spdx: "ISC", phrases: []string{"permission to use, copy, modify, and/or distribute this software for any purpose with or without fee is hereby granted"}},
+	{spdx: "MIT", phrases: []string{"permission is hereby granted, free of charge", "the above copyright notice and this permission notice shall be included"}},
+	{spdx: "BSD-3-Clause", phrases: []string{"redistribution and use in source and binary forms", "neither the name of"}},
+	{spdx: "BSD-2-Clause", phrases: []string{"redistribution and use in source and binary forms"}, excludes: []string{"neither the name of"}},
+}
+
+// Classifies license text by fingerprint and returns its SPDX identifier, or
+// NoAssertion when the text doesn't match a known license.
+func ClassifyLicense(text string) string {
+	normalized := normalizeLicense(text)
+
+	var head []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" && len(head) < licenseTitleLines {
+			head = append(head, line)
+		}
+	}
+	title := normalizeLicense(strings.Join(head, "\n"))
+
+	for _, fp := range licenseFingerprints {
+		searched := normalized
+		if fp.titled {
+			searched = title
+		}
+		if containsAll(searched, fp.phrases) && !containsAny(searched, fp.excludes) {
+			return fp.spdx
+		}
+	}
+	return NoAssertion
+}
+
+// Lowercases text and collapses its whitespace so phrases match across line
+// breaks
+func normalizeLicense(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+func containsAll(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if !strings.Contains(s, phrase) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsAny(s string, phrases []string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(s, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// Looks for a LICENSE* or COPYING* file at the root of a cloned repository and
+// classifies it. Returns empty strings when no license file exists.
+func DetectLicense(repoPath string) (file, spdx string, err error) {
+	entries, err := os.ReadDir(repoPath)
+	if err != nil {
+		return "", "", err
+	}
+
+	var candidates []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		upper := strings.ToUpper(entry.Name())
+		if strings.HasPrefix(upper, "LICENSE") || strings.HasPrefix(upper, "LICENCE") || strings.HasPrefix(upper, "COPYING") {
+			candidates = append(candidates, entry.Name())
+		}
+	}
+	if len(candidates) == 0 {
+		return "", "", nil
+	}
+
+	// prefer the shortest name, i.e. LICENSE over LICENSE-THIRD-PARTY
+	sort.Slice(candidates, func(i, j int) bool {
+		if len(candidates[i]) != len(candidates[j]) {
+			return len(candidates[i]) < len(candidates[j])
+		}
+		return candidates[i] < candidates[j]
+	})
+
+	content, err := os.ReadFile(path.Join(repoPath, candidates[0]))
+	if err != nil {
+		return "", "", err
+	}
+
+	return candidates[0], ClassifyLicense(string(content)), nil
+}
+
+// Audits the licenses of repositories from a JSON string, comparing the
+// license GitHub reports with the license file found in the reeled copy under
+// dirName, wherever reel cloned it. When allowlist is non-empty, repos whose license isn't in it are
+// marked as not allowed.
+func AuditLicenses(jsonStr, dirName string, allowlist []string) ([]LicenseReport, error) {
+	var repos []RepoModel
+	if err := json.Unmarshal([]byte(jsonStr), &repos); err != nil {
+		return nil, err
+	}
+
+	reports := make([]LicenseReport, 0, len(repos))
+	for _, repo := range repos {
+		report := LicenseReport{Name: repo.displayName(), URL: repo.URL}
+		if repo.License != nil {
+			report.APILicense = repo.License.SPDXID
+		}
+
+		repoPath, _ := cloneTarget(dirName, repo.Repo)
+		if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+			report.Status = LicenseNotReeled
+		} else {
+			file, spdx, err := DetectLicense(repoPath)
+			if err != nil {
+				return nil, err
+			}
+			report.File = file
+			report.FileLicense = spdx
+		}
+
+		if report.Status == "" {
+			report.Status = licenseStatus(report.APILicense, report.FileLicense)
+		} else if report.APILicense == "" {
+			report.Status = LicenseMissing
+		}
+
+		report.Allowed = licenseAllowed(effectiveLicense(report), allowlist)
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func licenseStatus(api, file string) string {
+	switch {
+	case api == "" && file == "":
+		return LicenseMissing
+	case api == "" || file == "":
+		return LicenseMismatch
+	case !strings.EqualFold(api, file):
+		return LicenseMismatch
+	default:
+		return LicenseOK
+	}
+}
+
+// The API license wins unless GitHub couldn't classify it
+func effectiveLicense(report LicenseReport) string {
+	if report.APILicense != "" && report.APILicense != NoAssertion {
+		return report.APILicense
+	}
+	if report.FileLicense != "" {
+		return report.FileLicense
+	}
+	return report.APILicense
+}
+
+func licenseAllowed(license string, allowlist []string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	for _, allowed := range allowlist {
+		if allowed != "" && strings.EqualFold(license, allowed) {
+			return true
+		}
+	}
+	return false
+}
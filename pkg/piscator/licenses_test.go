@@ -0,0 +1,173 @@
+package piscator
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const mitText = `MIT License
+
+Copyright (c) 2023 shimman-dev
+
+Permission is hereby granted, free of charge, to any person obtaining a copy
+of this software and associated documentation files (the "Software"), to deal
+in the Software without restriction.
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.`
+
+const apacheText = `                                 Apache License
+                           Version 2.0, January 2004
+                        http://www.apache.org/licenses/`
+
+const bsd2Text = `Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:`
+
+const bsd3Text = bsd2Text + `
+3. Neither the name of the copyright holder nor the names of its
+   contributors may be used to endorse or promote products derived from
+   this software without specific prior written permission.`
+
+func TestClassifyLicense(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"mit", mitText, "MIT"},
+		{"apache", apacheText, "Apache-2.0"},
+		{"gpl3", "GNU GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007", "GPL-3.0"},
+		{"gpl2", "GNU GENERAL PUBLIC LICENSE\n Version 2, June 1991", "GPL-2.0"},
+		{"agpl3", "GNU AFFERO GENERAL PUBLIC LICENSE\n Version 3, 19 November 2007", "AGPL-3.0"},
+		{"lgpl3", "GNU LESSER GENERAL PUBLIC LICENSE\n Version 3, 29 June 2007", "LGPL-3.0"},
+		{"bsd2", bsd2Text, "BSD-2-Clause"},
+		{"bsd3", bsd3Text, "BSD-3-Clause"},
+		{"mpl2", "Mozilla Public License Version 2.0\n==================================", "MPL-2.0"},
+		{"unlicense", "This is free and unencumbered software released into the public domain.", "Unlicense"},
+		{"unknown", "All rights reserved.", NoAssertion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ClassifyLicense(tt.text)
+			if got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// The full texts name other licenses in their bodies: GPL-3.0 mentions the
+// AGPL in section 13 and MPL-2.0 lists the GPL family as Secondary Licenses
+func TestClassifyLicenseFullText(t *testing.T) {
+	for _, spdx := range []string{"GPL-3.0", "AGPL-3.0", "LGPL-3.0", "MPL-2.0"} {
+		t.Run(spdx, func(t *testing.T) {
+			text, err := os.ReadFile(filepath.Join("testdata", "licenses", spdx+".txt"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ClassifyLicense(string(text)); got != spdx {
+				t.Errorf("Expected %v, got %v", spdx, got)
+			}
+		})
+	}
+}
+
+func TestAuditLicenses(t *testing.T) {
+	dir := t.TempDir()
+
+	writeLicense := func(repo, file, text string) {
+		if err := os.MkdirAll(path.Join(dir, repo), 0755); err != nil {
+			t.Fatalf("Failed to create repo directory: %v", err)
+		}
+		if file == "" {
+			return
+		}
+		if err := os.WriteFile(path.Join(dir, repo, file), []byte(text), 0644); err != nil {
+			t.Fatalf("Failed to write license file: %v", err)
+		}
+	}
+	writeLicense("matching", "LICENSE", mitText)
+	writeLicense("mismatched", "LICENSE.md", apacheText)
+	writeLicense("unlicensed", "", "")
+	writeLicense("file-only", "COPYING", bsd3Text)
+
+	jsonStr := `[
+		{"name": "matching", "html_url": "url1", "license": {"key": "mit", "spdx_id": "MIT"}},
+		{"name": "mismatched", "html_url": "url2", "license": {"key": "mit", "spdx_id": "MIT"}},
+		{"name": "unlicensed", "html_url": "url3"},
+		{"name": "file-only", "html_url": "url4", "license": {"key": "other", "spdx_id": "NOASSERTION"}},
+		{"name": "remote-only", "html_url": "url5", "license": {"key": "mit", "spdx_id": "MIT"}}
+	]`
+
+	reports, err := AuditLicenses(jsonStr, dir, []string{"MIT", "BSD-3-Clause"})
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	expected := map[string]struct {
+		status  string
+		file    string
+		allowed bool
+	}{
+		"matching":    {LicenseOK, "MIT", true},
+		"mismatched":  {LicenseMismatch, "Apache-2.0", true},
+		"unlicensed":  {LicenseMissing, "", false},
+		"file-only":   {LicenseMismatch, "BSD-3-Clause", true},
+		"remote-only": {LicenseNotReeled, "", true},
+	}
+
+	if len(reports) != len(expected) {
+		t.Fatalf("Expected %d reports, got %d", len(expected), len(reports))
+	}
+	for _, report := range reports {
+		want := expected[report.Name]
+		if report.Status != want.status {
+			t.Errorf("%s: expected status %v, got %v", report.Name, want.status, report.Status)
+		}
+		if report.FileLicense != want.file {
+			t.Errorf("%s: expected file license %v, got %v", report.Name, want.file, report.FileLicense)
+		}
+		if report.Allowed != want.allowed {
+			t.Errorf("%s: expected allowed %v, got %v", report.Name, want.allowed, report.Allowed)
+		}
+	}
+
+	if _, err := AuditLicenses(`{[}`, dir, nil); err == nil {
+		t.Errorf("Expected an error but did not get one")
+	}
+}
+
+func TestAuditLicensesCloneLayout(t *testing.T) {
+	dir := t.TempDir()
+	for _, repo := range []string{"spf13/cobra", "octocat/cobra", "gists/aa11"} {
+		if err := os.MkdirAll(path.Join(dir, repo), 0755); err != nil {
+			t.Fatalf("Failed to create repo directory: %v", err)
+		}
+		if err := os.WriteFile(path.Join(dir, repo, "LICENSE"), []byte(mitText), 0644); err != nil {
+			t.Fatalf("Failed to write license file: %v", err)
+		}
+	}
+
+	jsonStr := `[
+		{"name": "cobra", "html_url": "https://github.com/spf13/cobra", "by_owner": true, "license": {"spdx_id": "MIT"}},
+		{"name": "cobra", "html_url": "https://github.com/octocat/cobra", "by_owner": true, "license": {"spdx_id": "MIT"}},
+		{"name": "aa11", "html_url": "https://gist.github.com/aa11", "gist": true, "license": {"spdx_id": "MIT"}}
+	]`
+	reports, err := AuditLicenses(jsonStr, dir, nil)
+	if err != nil {
+		t.Fatalf("Did not expect an error but got: %v", err)
+	}
+
+	var got []string
+	for _, report := range reports {
+		got = append(got, report.Name+" "+report.Status)
+	}
+	want := []string{"spf13/cobra " + LicenseOK, "octocat/cobra " + LicenseOK, "aa11 " + LicenseOK}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
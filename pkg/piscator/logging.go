@@ -0,0 +1,80 @@
+package piscator
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// LogObserver is an Observer that writes every event to a structured logger.
+// Routine progress is logged at debug, finished repos at info, retries and
+// rate limiting at warn and failed repos at error, with the repo name attached
+// as an attribute.
+type LogObserver struct {
+	logger *slog.Logger
+}
+
+// Creates a LogObserver writing to logger, or to slog.Default when nil.
+func NewLogObserver(logger *slog.Logger) *LogObserver {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &LogObserver{logger: logger}
+}
+
+func (o *LogObserver) Observe(event Event) {
+	ctx := context.Background()
+
+	switch e := event.(type) {
+	case ListPageFetched:
+		o.logger.Debug("fetched page", "url", e.URL, "page", e.Page, "count", e.Count)
+	case RequestRetried:
+		attrs := []any{"url", e.URL, "attempt", e.Attempt}
+		if e.StatusCode != 0 {
+			attrs = append(attrs, "status", e.StatusCode)
+		}
+		if e.Err != nil {
+			attrs = append(attrs, "err", e.Err)
+		}
+		o.logger.Warn("retrying request", attrs...)
+	case RateLimited:
+		o.logger.Warn("rate limit exceeded, sleeping", "url", e.URL, "until", e.Until.Round(time.Second), "wait", e.Wait.Round(time.Second))
+	case ReelStarted:
+		o.logger.Info("reeling repos", "dir", e.Dir, "total", e.Total)
+	case RepoStarted:
+		o.logger.Debug("reeling repo", "repo", e.Repo.displayName(), "url", e.Repo.URL)
+	case RepoProgress:
+		// git reports progress many times a second, only note finished phases
+		if e.Percent == 100 {
+			o.logger.Debug("git phase done", "repo", e.Repo.displayName(), "phase", e.Phase)
+		}
+	case RepoFinished:
+		attrs := []slog.Attr{
+			slog.String("repo", e.Repo.displayName()),
+			slog.String("action", e.Result.Action),
+			slog.Duration("duration", e.Result.Duration.Round(time.Millisecond)),
+		}
+		if e.Result.Err != nil {
+			// git says why it failed on stderr, the error is only its exit status
+			attrs = append(attrs, slog.Any("err", e.Result.Err), slog.String("output", gitErrorOutput(e.Result.Output)))
+			o.logger.LogAttrs(ctx, slog.LevelError, "failed to reel repo", attrs...)
+		} else {
+			o.logger.LogAttrs(ctx, slog.LevelInfo, "reeled repo", attrs...)
+		}
+	case ReelFinished:
+		o.logger.Info("reel finished", "cloned", e.Total-e.Failed, "failed", e.Failed)
+	}
+}
+
+// Returns what git printed without its progress lines, which a streamed clone
+// or pull also captures
+func gitErrorOutput(out []byte) string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(string(out), func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if _, _, ok := parseGitProgress(line); !ok && strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimSpace(line))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,50 @@
+package piscator
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogObserver(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	observer := NewLogObserver(logger)
+
+	repo1, repo2 := Repo{Name: "repo1"}, Repo{Name: "repo2"}
+	observer.Observe(ListPageFetched{URL: "https://api.github.com/users/shimman-dev/repos", Page: 1, Count: 2})
+	observer.Observe(RequestRetried{URL: "https://api.github.com/users/shimman-dev/repos", Attempt: 1, StatusCode: 502})
+	observer.Observe(RateLimited{Until: time.Now().Add(time.Minute), Wait: time.Minute})
+	observer.Observe(ReelStarted{Dir: "shimman-dev", Total: 2})
+	observer.Observe(RepoStarted{Repo: repo1})
+	observer.Observe(RepoProgress{Repo: repo1, Phase: PhaseReceiving, Percent: 50})
+	observer.Observe(RepoProgress{Repo: repo1, Phase: PhaseReceiving, Percent: 100})
+	observer.Observe(RepoFinished{Repo: repo1, Result: RepoResult{Action: ActionClone}})
+	observer.Observe(RepoFinished{Repo: repo2, Result: RepoResult{Action: ActionPull, Err: errors.New("boom"), Output: []byte("Receiving objects:  45% (450/1000)\rfatal: couldn't find remote ref main\n")}})
+	observer.Observe(ReelFinished{Total: 2, Failed: 1})
+
+	got := out.String()
+	for _, want := range []string{
+		`level=DEBUG msg="fetched page"`,
+		`level=WARN msg="retrying request"`,
+		"status=502",
+		`level=WARN msg="rate limit exceeded, sleeping"`,
+		`level=INFO msg="reeling repos" dir=shimman-dev total=2`,
+		`msg="git phase done" repo=repo1 phase=receiving`,
+		`level=INFO msg="reeled repo" repo=repo1 action=clone`,
+		`level=ERROR msg="failed to reel repo" repo=repo2 action=pull`,
+		"err=boom",
+		`output="fatal: couldn't find remote ref main"`,
+		`msg="reel finished" cloned=1 failed=1`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, got)
+		}
+	}
+	if strings.Count(got, "git phase done") != 1 {
+		t.Errorf("Expected only finished git phases to be logged, got %q", got)
+	}
+}
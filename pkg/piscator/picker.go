@@ -0,0 +1,319 @@
+package piscator
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrPickerCancelled is returned by PickRepos when the user aborts the picker
+var ErrPickerCancelled = errors.New("repository selection cancelled")
+
+// number of repos shown at once in the picker
+const pickerHeight = 15
+
+const (
+	keyCtrlA     = 1
+	keyCtrlC     = 3
+	keyBackspace = 8
+	keyTab       = 9
+	keyEnter     = 13
+	keyNewline   = 10
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+type pickerState struct {
+	repos    []RepoModel
+	query    string
+	matches  []int // indexes into repos, best match first
+	cursor   int
+	selected map[int]bool
+}
+
+// Presents repositories from a JSON string as a fuzzy-finder style list read
+// from in and drawn to out, allowing several to be selected, and returns the
+// selected repositories as a JSON string.
+//
+// The caller is responsible for putting the terminal in raw mode. Type to
+// filter, arrows or ctrl-n/ctrl-p move, tab toggles, ctrl-a toggles all
+// matches, enter confirms and esc or ctrl-c cancels.
+func PickRepos(in io.Reader, out io.Writer, jsonStr string) (string, error) {
+	var repos []RepoModel
+	if err := json.Unmarshal([]byte(jsonStr), &repos); err != nil {
+		return "", err
+	}
+
+	state := &pickerState{repos: repos, selected: map[int]bool{}}
+	state.filter()
+
+	reader := bufio.NewReader(in)
+	for {
+		renderPicker(out, state)
+
+		b, err := reader.ReadByte()
+		if err != nil {
+			return "", ErrPickerCancelled
+		}
+
+		switch b {
+		case keyCtrlC:
+			clearPicker(out)
+			return "", ErrPickerCancelled
+		case keyEscape:
+			// arrow keys arrive as ESC [ A/B, a lone ESC cancels
+			if reader.Buffered() == 0 {
+				clearPicker(out)
+				return "", ErrPickerCancelled
+			}
+			if next, _ := reader.ReadByte(); next != '[' {
+				continue
+			}
+			switch arrow, _ := reader.ReadByte(); arrow {
+			case 'A':
+				state.move(-1)
+			case 'B':
+				state.move(1)
+			}
+		case keyCtrlP:
+			state.move(-1)
+		case keyCtrlN:
+			state.move(1)
+		case keyTab:
+			state.toggle()
+			state.move(1)
+		case keyCtrlA:
+			state.toggleAll()
+		case keyCtrlU:
+			state.query = ""
+			state.filter()
+		case keyBackspace, keyDelete:
+			if state.query != "" {
+				runes := []rune(state.query)
+				state.query = string(runes[:len(runes)-1])
+				state.filter()
+			}
+		case keyEnter, keyNewline:
+			clearPicker(out)
+			return state.result()
+		default:
+			if b >= ' ' && b < keyDelete {
+				state.query += string(b)
+				state.filter()
+			} else if r := readRune(reader, b); r != utf8.RuneError && unicode.IsPrint(r) {
+				state.query += string(r)
+				state.filter()
+			}
+		}
+	}
+}
+
+// Decodes the UTF-8 character starting with b, reading the rest of its bytes.
+// Returns utf8.RuneError for control bytes and invalid input.
+func readRune(reader *bufio.Reader, b byte) rune {
+	if b < utf8.RuneSelf {
+		return utf8.RuneError
+	}
+	buf := []byte{b}
+	for !utf8.FullRune(buf) {
+		next, err := reader.ReadByte()
+		if err != nil {
+			return utf8.RuneError
+		}
+		buf = append(buf, next)
+	}
+	r, _ := utf8.DecodeRune(buf)
+	return r
+}
+
+func (s *pickerState) filter() {
+	type scored struct {
+		index int
+		score int
+	}
+
+	var results []scored
+	for i, repo := range s.repos {
+		if score, ok := fuzzyScore(repo.Name, s.query); ok {
+			results = append(results, scored{i, score})
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	s.matches = s.matches[:0]
+	for _, r := range results {
+		s.matches = append(s.matches, r.index)
+	}
+	s.cursor = 0
+}
+
+func (s *pickerState) move(delta int) {
+	if len(s.matches) == 0 {
+		return
+	}
+	s.cursor = (s.cursor + delta + len(s.matches)) % len(s.matches)
+}
+
+func (s *pickerState) toggle() {
+	if len(s.matches) == 0 {
+		return
+	}
+	i := s.matches[s.cursor]
+	s.selected[i] = !s.selected[i]
+}
+
+// Selects every match, or deselects them when they're all already selected
+func (s *pickerState) toggleAll() {
+	all := true
+	for _, i := range s.matches {
+		if !s.selected[i] {
+			all = false
+			break
+		}
+	}
+	for _, i := range s.matches {
+		s.selected[i] = !all
+	}
+}
+
+// Returns the selected repos in listing order, falling back to the repo under
+// the cursor when nothing was explicitly selected.
+func (s *pickerState) result() (string, error) {
+	picked := []RepoModel{}
+	for i, repo := range s.repos {
+		if s.selected[i] {
+			picked = append(picked, repo)
+		}
+	}
+	if len(picked) == 0 && len(s.matches) > 0 {
+		picked = append(picked, s.repos[s.matches[s.cursor]])
+	}
+
+	jsonData, err := json.MarshalIndent(picked, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+func renderPicker(out io.Writer, s *pickerState) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&b, "> %s\r\n", s.query)
+
+	// keep the cursor within the visible window
+	start := 0
+	if s.cursor >= pickerHeight {
+		start = s.cursor - pickerHeight + 1
+	}
+	end := start + pickerHeight
+	if end > len(s.matches) {
+		end = len(s.matches)
+	}
+
+	for pos := start; pos < end; pos++ {
+		i := s.matches[pos]
+		repo := s.repos[i]
+
+		pointer := " "
+		if pos == s.cursor {
+			pointer = ">"
+		}
+		mark := " "
+		if s.selected[i] {
+			mark = "*"
+		}
+		pushed := repo.PushedAt
+		if len(pushed) >= 10 {
+			pushed = pushed[:10]
+		}
+
+		fmt.Fprintf(&b, "%s%s %-40s %-12s %8s  %s\r\n", pointer, mark, truncate(repo.Name, 40), truncate(repo.Lang, 12), formatSize(repo.Size), pushed)
+	}
+
+	fmt.Fprintf(&b, "  %d/%d matches, %d selected (tab: toggle, ctrl-a: all, enter: reel)\r\n", len(s.matches), len(s.repos), countSelected(s.selected))
+	io.WriteString(out, b.String())
+}
+
+func clearPicker(out io.Writer) {
+	io.WriteString(out, "\x1b[H\x1b[2J")
+}
+
+func countSelected(selected map[int]bool) int {
+	n := 0
+	for _, v := range selected {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// GitHub reports repo sizes in kilobytes
+func formatSize(kb uint) string {
+	switch {
+	case kb >= 1024*1024:
+		return fmt.Sprintf("%.1fG", float64(kb)/(1024*1024))
+	case kb >= 1024:
+		return fmt.Sprintf("%.1fM", float64(kb)/1024)
+	default:
+		return fmt.Sprintf("%dK", kb)
+	}
+}
+
+// Scores how well query fuzzy matches s, where every character of the query
+// must appear in order. Consecutive characters and matches at the start of a
+// word score higher.
+func fuzzyScore(s, query string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	target := []rune(strings.ToLower(s))
+	score, last, qi := 0, -2, 0
+	needle := []rune(strings.ToLower(query))
+
+	for ti, r := range target {
+		if qi == len(needle) {
+			break
+		}
+		if r != needle[qi] {
+			continue
+		}
+
+		score++
+		if ti == last+1 {
+			score += 3
+		}
+		if ti == 0 || !unicode.IsLetter(target[ti-1]) && !unicode.IsDigit(target[ti-1]) {
+			score += 2
+		}
+		last = ti
+		qi++
+	}
+
+	if qi < len(needle) {
+		return 0, false
+	}
+	// prefer shorter names when the match is otherwise equal
+	return score*100 - len(target), true
+}
@@ -0,0 +1,97 @@
+package piscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		s       string
+		query   string
+		matches bool
+	}{
+		{"piscator", "", true},
+		{"piscator", "pst", true},
+		{"piscator", "PIS", true},
+		{"piscator", "tsp", false},
+		{"eslint-config", "ec", true},
+		{"eslint-config", "xyz", false},
+	}
+
+	for _, tt := range tests {
+		_, ok := fuzzyScore(tt.s, tt.query)
+		if ok != tt.matches {
+			t.Errorf("Expected fuzzyScore(%q, %q) match to be %v, got %v", tt.s, tt.query, tt.matches, ok)
+		}
+	}
+
+	prefix, _ := fuzzyScore("config", "con")
+	scattered, _ := fuzzyScore("c-o-n", "con")
+	if prefix <= scattered {
+		t.Errorf("Expected consecutive match to score higher, got %d <= %d", prefix, scattered)
+	}
+}
+
+func TestPickRepos(t *testing.T) {
+	jsonStr := `[
+		{"name": "piscator", "html_url": "url1", "language": "Go"},
+		{"name": "eslint-config", "html_url": "url2", "language": "JavaScript"},
+		{"name": "knockerupper", "html_url": "url3"},
+		{"name": "café-münchen", "html_url": "url4"}
+	]`
+
+	tests := []struct {
+		name      string
+		input     string
+		expected  []string
+		wantError error
+	}{
+		{"enter picks cursor", "\r", []string{"piscator"}, nil},
+		{"filter and pick", "kno\r", []string{"knockerupper"}, nil},
+		{"multi select", "\t\t\r", []string{"piscator", "eslint-config"}, nil},
+		{"arrow keys", "\x1b[B\x1b[B\x1b[A\r", []string{"eslint-config"}, nil},
+		{"backspace", "xyz\x7f\x7f\x7fesl\r", []string{"eslint-config"}, nil},
+		{"select all", "\x01\r", []string{"piscator", "eslint-config", "knockerupper", "café-münchen"}, nil},
+		{"utf-8 query", "éü\r", []string{"café-münchen"}, nil},
+		{"utf-8 backspace", "ü\x7fkno\r", []string{"knockerupper"}, nil},
+		{"invalid utf-8 ignored", "\xffkno\r", []string{"knockerupper"}, nil},
+		{"no matches", "zzz\r", []string{}, nil},
+		{"ctrl-c cancels", "\t\x03", nil, ErrPickerCancelled},
+		{"escape cancels", "\x1b", nil, ErrPickerCancelled},
+		{"eof cancels", "pis", nil, ErrPickerCancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := PickRepos(strings.NewReader(tt.input), &out, jsonStr)
+			if !errors.Is(err, tt.wantError) {
+				t.Fatalf("PickRepos() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantError != nil {
+				return
+			}
+
+			var repos []RepoModel
+			if err := json.Unmarshal([]byte(got), &repos); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			names := []string{}
+			for _, repo := range repos {
+				names = append(names, repo.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	if _, err := PickRepos(strings.NewReader("\r"), &bytes.Buffer{}, `{[}`); err == nil {
+		t.Errorf("Expected an error but did not get one")
+	}
+}
@@ -1,9 +1,11 @@
 package piscator
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -12,25 +14,62 @@ import (
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
-
-	"github.com/briandowns/spinner"
 )
 
 // Repo is a struct for a GitHub repository
 type Repo struct {
 	Name string `json:"name"`
 	URL  string `json:"html_url"`
+
+	// gists are cloned from their git_pull_url into a gists/ subtree
+	GitPullURL string `json:"git_pull_url,omitempty"`
+	Gist       bool   `json:"gist,omitempty"`
+
+	// Branch is checked out instead of the remote's default when cloning
+	Branch string `json:"branch,omitempty"`
+
+	// repos listed from several owners, such as stars and search results, are
+	// cloned into owner/name so repos sharing a name don't collide
+	ByOwner bool `json:"by_owner,omitempty"`
+}
+
+// Returns the name a repo is shown by, owner/name when it's cloned by owner
+func (r Repo) displayName() string {
+	if r.ByOwner {
+		return path.Join(RepoOwnerFromURL(r.URL), r.Name)
+	}
+	return r.Name
+}
+
+// License is the license GitHub detected for a repository
+type License struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	SPDXID string `json:"spdx_id"`
 }
 
 // RepoModel is the struct for a GitHub repository
 type RepoModel struct {
-	Repo           // embed Repo struct
-	Lang    string `json:"language"`
-	Fork    bool   `json:"fork"`
-	Private bool   `json:"private"`
-	Size    uint   `json:"size"`
+	Repo                // embed Repo struct
+	ID         int64    `json:"id"`
+	Lang       string   `json:"language"`
+	Fork       bool     `json:"fork"`
+	Private    bool     `json:"private"`
+	Visibility string   `json:"visibility"`
+	Archived   bool     `json:"archived"`
+	Size       uint     `json:"size"`
+	PushedAt   string   `json:"pushed_at,omitempty"`
+	License    *License `json:"license,omitempty"`
+
+	Description string `json:"description,omitempty"`
+	StarredAt   string `json:"starred_at,omitempty"`
+
+	SSHURL        string   `json:"ssh_url,omitempty"`
+	CloneURL      string   `json:"clone_url,omitempty"`
+	DefaultBranch string   `json:"default_branch,omitempty"`
+	Topics        []string `json:"topics,omitempty"`
+	Languages     []string `json:"languages,omitempty"`
 }
 
 // RepoCollection is a collection of RepoModel structs
@@ -58,89 +97,175 @@ func (rs RealSleeper) Sleep(d time.Duration) {
 	time.Sleep(d)
 }
 
+// repos requested per REST page, the most GitHub allows
+const restPageSize = 100
+
+// ListOptions narrows a REST listing, each field maps onto a GitHub query
+// parameter or endpoint. The zero value lists everything.
+type ListOptions struct {
+	// Team lists the repos of an org team by its slug
+	Team string
+	// Affiliation and Visibility filter --self listings
+	Affiliation []string
+	Visibility  string
+	// Type filters org listings: sources, forks, member, internal and so on
+	Type string
+}
+
+// Checks if no option is set
+func (o ListOptions) isZero() bool {
+	return o.Team == "" && len(o.Affiliation) == 0 && o.Visibility == "" && o.Type == ""
+}
+
+// Checks the options against the values GitHub accepts for the listing mode
+func (o ListOptions) validate(isSelf, isOrg bool) error {
+	if o.Team != "" && !isOrg {
+		return errors.New("--team only applies to --org")
+	}
+	if o.Team != "" && o.Type != "" {
+		return errors.New("--team can't be combined with --type")
+	}
+	if (len(o.Affiliation) > 0 || o.Visibility != "") && !isSelf {
+		return errors.New("--affiliation and --visibility only apply to --self")
+	}
+	for _, a := range o.Affiliation {
+		if a != "owner" && a != "collaborator" && a != "organization_member" {
+			return fmt.Errorf("invalid affiliation %q, expected owner, collaborator or organization_member", a)
+		}
+	}
+	if o.Visibility != "" && o.Visibility != "all" && o.Visibility != "public" && o.Visibility != "private" {
+		return fmt.Errorf("invalid visibility %q, expected all, public or private", o.Visibility)
+	}
+	if o.Type != "" {
+		if !isOrg {
+			return errors.New("--type only applies to --org")
+		}
+		switch o.Type {
+		case "all", "public", "private", "forks", "sources", "member", "internal":
+		default:
+			return fmt.Errorf("invalid type %q, expected all, public, private, forks, sources, member or internal", o.Type)
+		}
+	}
+	return nil
+}
+
 // Retrieves repositories of a user/organization/self from GitHub.
-// Optionally filters based on fork status, and returns them as a JSON string or
-// writes to a file.
+// Optionally filters based on fork status, and returns them as a JSON string.
+// Every page is fetched, and pages, retries and rate
+// limiting are reported to observer, which may be nil.
 //
-// Please note, name represents a GitHub user/org name, while username is
-// intended for enterprise GitHub accounts.
-func GetRepos(client HttpClient, sleeper Sleeper, name, token, username, password, enterpriseHost string, isSelf, isOrg, isForked, makeFile bool) (string, error) {
-	var githubURL string
+// Please note, name represents a GitHub user/org name, while username and
+// password are basic auth credentials. enterpriseHost is a GitHub Enterprise
+// Server host, github.com when empty.
+func GetRepos(client HttpClient, sleeper Sleeper, observer Observer, name, token, username, password, enterpriseHost string, isSelf, isOrg, isForked bool, opts ListOptions) (string, error) {
+	if err := opts.validate(isSelf, isOrg); err != nil {
+		return "", err
+	}
 
-	gh, err := url.Parse("https://api.github.com/")
+	gh, err := url.Parse(APIBaseURL(enterpriseHost))
 	if err != nil {
 		return "", err
 	}
 
+	params := url.Values{}
+	params.Add("per_page", strconv.Itoa(restPageSize))
 	switch {
 	case isSelf:
-		gh.Path = path.Join("user", "repos")
-	case isOrg:
-		gh.Path = path.Join("orgs", name, "repos")
-		if username != "" && password != "" {
-			gh.User = url.UserPassword(username, password)
+		gh.Path = path.Join(gh.Path, "user", "repos")
+		if len(opts.Affiliation) > 0 {
+			params.Add("affiliation", strings.Join(opts.Affiliation, ","))
 		}
-		if enterpriseHost != "" {
-			gh.Host = enterpriseHost
-			log.Printf("github host: %s", gh.Host)
+		if opts.Visibility != "" {
+			params.Add("visibility", opts.Visibility)
+		}
+	case isOrg && opts.Team != "":
+		gh.Path = path.Join(gh.Path, "orgs", name, "teams", opts.Team, "repos")
+	case isOrg:
+		gh.Path = path.Join(gh.Path, "orgs", name, "repos")
+		if opts.Type != "" {
+			params.Add("type", opts.Type)
 		}
 	default:
-		gh.Path = path.Join("users", name, "repos")
+		gh.Path = path.Join(gh.Path, "users", name, "repos")
 	}
+	gh.RawQuery = params.Encode()
 
-	params := url.Values{}
-	params.Add("per_page", "1000")
-	githubURL = gh.String() + "?" + params.Encode()
+	// asking for forks only makes no sense if they're then dropped
+	if opts.Type == "forks" {
+		isForked = true
+	}
 
-	var res *http.Response
-	for i := 0; i < 3; i++ {
-		req, err := http.NewRequest("GET", githubURL, nil)
-		if err != nil {
-			log.Fatalf("Error creating request: %v", err)
+	var repos []RepoModel
+	err = fetchPages(NewRetryClient(client, sleeper, observer), observer, gh.String(), func(req *http.Request) {
+		if username != "" && password != "" {
+			req.SetBasicAuth(username, password)
 		}
-		req.Header.Set("User-Agent", "shimman-dev/piscator")
 		if token != "" {
 			req.Header.Set("Accept", "application/vnd.github+json")
 			req.Header.Set("Authorization", "Bearer "+token)
 		}
-		res, err = client.Do(req)
+	}, func(body io.Reader) (int, error) {
+		var page []RepoModel
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return 0, err
+		}
+		repos = append(repos, page...)
+		return len(page), nil
+	})
+	if err != nil {
+		return "", err
+	}
 
+	return finishRepos(repos, isForked)
+}
+
+// Fetches a REST listing page by page, following the Link header until there
+// is no next page. prepare sets the headers of each request and decode reads
+// a page, returning how many items it held.
+func fetchPages(rc *RetryClient, observer Observer, pageURL string, prepare func(req *http.Request), decode func(body io.Reader) (int, error)) error {
+	for page := 1; pageURL != ""; page++ {
+		req, err := http.NewRequest("GET", pageURL, nil)
 		if err != nil {
-			log.Printf("Attempt %d: failed to get repos: %v", i+1, err)
-			sleeper.Sleep(2 * time.Second)
-			continue
+			return fmt.Errorf("error creating request: %w", err)
 		}
-		defer res.Body.Close()
+		req.Header.Set("User-Agent", "shimman-dev/piscator")
+		prepare(req)
 
-		if res.StatusCode != http.StatusOK {
-			log.Printf("Attempt %d: unexpected status code: %d", i+1, res.StatusCode)
-			sleeper.Sleep(2 * time.Second)
-			continue
+		res, err := rc.Do(req)
+		if err != nil {
+			return err
 		}
-
-		if remaining := res.Header.Get("X-Ratelimit-Remaining"); remaining == "0" {
-			resetTimeStr := res.Header.Get("X-Ratelimit-Reset")
-			resetTimeUnix, _ := strconv.ParseInt(resetTimeStr, 10, 64)
-			resetTime := time.Unix(resetTimeUnix, 0)
-			log.Printf("Attempt %d: rate limit exceeded, sleeping until %v", i+1, resetTime)
-			sleeper.Sleep(time.Until(resetTime))
-		} else {
-			break
+		count, err := decode(res.Body)
+		res.Body.Close()
+		if err != nil {
+			return err
 		}
+		notify(observer, ListPageFetched{URL: pageURL, Page: page, Count: count})
+
+		pageURL = nextPageURL(res.Header.Get("Link"))
 	}
-	if err != nil {
-		return "", err
-	}
-	if res == nil {
-		return "", fmt.Errorf("failed to get repos after 3 attempts")
-	}
+	return nil
+}
 
-	var repos []RepoModel
-	err = json.NewDecoder(res.Body).Decode(&repos)
-	if err != nil {
-		return "", err
+// Returns the rel="next" URL of a Link header, empty on the last page
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		fields := strings.Split(part, ";")
+		if len(fields) < 2 {
+			continue
+		}
+		for _, param := range fields[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return strings.Trim(strings.TrimSpace(fields[0]), "<>")
+			}
+		}
 	}
+	return ""
+}
 
+// Drops forks unless isForked and repos missing a name or URL, then returns
+// the repos as a JSON string.
+func finishRepos(repos []RepoModel, isForked bool) (string, error) {
 	filteredRepos := []RepoModel{}
 	if isForked {
 		for _, repo := range repos {
@@ -162,21 +287,6 @@ func GetRepos(client HttpClient, sleeper Sleeper, name, token, username, passwor
 		return "", err
 	}
 
-	if makeFile {
-		// Open file to write
-		file, err := os.Create("repos.json")
-		if err != nil {
-			return "", err
-		}
-		defer file.Close()
-
-		// Write JSON to file
-		_, err = file.Write(jsonData)
-		if err != nil {
-			return "", err
-		}
-	}
-
 	return string(jsonData), nil
 }
 
@@ -222,21 +332,90 @@ type CommandExecutor interface {
 	ExecuteCommandInDir(dir, name string, arg ...string) ([]byte, error)
 }
 
-type RealCommandExecutor struct{}
+// StreamingCommandExecutor is implemented by executors that can report output
+// line by line while a command runs, used to follow `git --progress`.
+type StreamingCommandExecutor interface {
+	CommandExecutor
+	ExecuteCommandStream(dir string, onLine func(line string), name string, arg ...string) ([]byte, error)
+}
 
-func (r RealCommandExecutor) ExecuteCommand(name string, arg ...string) ([]byte, error) {
+// EnvCommandExecutor is implemented by executors that can run commands with
+// extra environment variables, used to hand credentials to git.
+type EnvCommandExecutor interface {
+	WithEnv(env ...string) CommandExecutor
+}
+
+// RealCommandExecutor runs commands on the host, Env is added to the
+// environment inherited from piscator.
+type RealCommandExecutor struct {
+	Env []string
+}
+
+func (r RealCommandExecutor) WithEnv(env ...string) CommandExecutor {
+	return RealCommandExecutor{Env: append(append([]string{}, r.Env...), env...)}
+}
+
+func (r RealCommandExecutor) command(name string, arg ...string) *exec.Cmd {
 	cmd := exec.Command(name, arg...)
+	if len(r.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
+	return cmd
+}
+
+func (r RealCommandExecutor) ExecuteCommand(name string, arg ...string) ([]byte, error) {
+	cmd := r.command(name, arg...)
 	return cmd.CombinedOutput()
 }
 
 func (r RealCommandExecutor) ExecuteCommandInDir(dir, name string, arg ...string) ([]byte, error) {
-	cmd := exec.Command(name, arg...)
+	cmd := r.command(name, arg...)
 	cmd.Dir = dir
 	return cmd.CombinedOutput()
 }
 
-// Clones GitHub repositories from a JSON string concurrently, updates if they already exist, and logs progress.
-func CloneReposFromJson(executor CommandExecutor, jsonStr, dirName string, concurrentLimit int8, verboseLog bool) error {
+func (r RealCommandExecutor) ExecuteCommandStream(dir string, onLine func(line string), name string, arg ...string) ([]byte, error) {
+	cmd := r.command(name, arg...)
+	cmd.Dir = dir
+	w := &lineWriter{onLine: onLine}
+	// the same writer for both streams means exec never writes concurrently
+	cmd.Stdout = w
+	cmd.Stderr = w
+	err := cmd.Run()
+	w.flush()
+	return w.out.Bytes(), err
+}
+
+// lineWriter collects output and calls onLine for every line, git separates
+// progress updates with carriage returns so those end a line too
+type lineWriter struct {
+	out     bytes.Buffer
+	partial []byte
+	onLine  func(line string)
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.out.Write(b)
+	for _, c := range b {
+		if c == '\r' || c == '\n' {
+			w.flush()
+			continue
+		}
+		w.partial = append(w.partial, c)
+	}
+	return len(b), nil
+}
+
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 && w.onLine != nil {
+		w.onLine(string(w.partial))
+	}
+	w.partial = w.partial[:0]
+}
+
+// Clones GitHub repositories from a JSON string concurrently and updates them
+// if they already exist, reporting progress to observer.
+func CloneReposFromJson(executor CommandExecutor, observer Observer, jsonStr, dirName string, concurrentLimit int8) error {
 	// unmarshal the JSON string into a slice of Repo structs
 	var repos []Repo
 	if err := json.Unmarshal([]byte(jsonStr), &repos); err != nil {
@@ -255,13 +434,11 @@ func CloneReposFromJson(executor CommandExecutor, jsonStr, dirName string, concu
 	var wg sync.WaitGroup
 	wg.Add(len(repos))
 
-	var counter uint64 = 1
 	sem := make(chan struct{}, concurrentLimit)
 
-	s := spinner.New(spinner.CharSets[11], 100*time.Millisecond)
-	s.Start()
+	notify(observer, ReelStarted{Dir: dir, Total: len(repos)})
 
-	errors := make(chan error) // Create a new error channel
+	errors := make(chan error, len(repos)) // buffered so no clone blocks on a failure
 
 	// clone each repo in a separate goroutine
 	for _, repo := range repos {
@@ -270,52 +447,20 @@ func CloneReposFromJson(executor CommandExecutor, jsonStr, dirName string, concu
 			defer func() { <-sem }()
 			defer wg.Done()
 
-			repoPath := path.Join(dir, repo.Name)
-			var cmdOut []byte
-			var err error
-			if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-				// repo doesn't exist, clone it
-				cloneCmd := []string{"git", "clone"}
-				if isSSHURL(repo.URL) {
-					cloneCmd = append(cloneCmd, "--ssh")
-				}
-				cloneCmd = append(cloneCmd, repo.URL, repoPath)
-				cmdOut, err = executor.ExecuteCommand(cloneCmd[0], cloneCmd[1:]...)
-
-				if err != nil {
-					errors <- fmt.Errorf("error cloning repo: %w", err) // Send error to channel
-					return
-				}
-			} else if err != nil {
-				errors <- fmt.Errorf("error checking if repo exists: %w", err)
-				return
-			} else {
-				// repo exists, pull latest changes
-				cmdOut, err = executor.ExecuteCommandInDir(repoPath, "git", "pull")
-				if err != nil {
-					errors <- fmt.Errorf("error pulling latest changes: %w", err)
-					return
-				}
-			}
-			if err != nil {
-				fmt.Printf("failed to clone %s: %s\n", repo.URL, string(cmdOut))
+			notify(observer, RepoStarted{Repo: repo})
+			result := syncRepo(executor, observer, dir, repo)
+			notify(observer, RepoFinished{Repo: repo, Result: result})
+			if result.Err != nil {
+				errors <- result.Err
 			}
-
-			if verboseLog {
-				// TODO: more succinct messaging
-				log.Printf("Cloned %s/%s\n", dir, repo.Name)
-			}
-
-			atomic.AddUint64(&counter, 1)
-			s.Suffix = fmt.Sprintf(" Cloning %d/%d repos\n", counter, len(repos))
 		}(repo)
 	}
 
 	// wait for all clones to finish
-	go func() {
-		wg.Wait()
-		close(errors) // Close the channel when all goroutines are done
-	}()
+	wg.Wait()
+	close(errors)
+
+	notify(observer, ReelFinished{Total: len(repos), Failed: len(errors)})
 
 	// Check for any errors from the goroutines
 	for err := range errors {
@@ -324,16 +469,68 @@ func CloneReposFromJson(executor CommandExecutor, jsonStr, dirName string, concu
 		}
 	}
 
-	s.Stop()
-	fmt.Printf("Cloned %d repos\n", len(repos))
 	return nil
 }
 
-// Checks if the URL is using the SSH scheme
-func isSSHURL(urlStr string) bool {
-	u, err := url.Parse(urlStr)
-	if err != nil {
-		return false
+// Clones a repo into dir, or pulls the latest changes when it already exists
+func syncRepo(executor CommandExecutor, observer Observer, dir string, repo Repo) RepoResult {
+	start := time.Now()
+	onLine := func(line string) {
+		if phase, percent, ok := parseGitProgress(line); ok {
+			notify(observer, RepoProgress{Repo: repo, Phase: phase, Percent: percent})
+		}
+	}
+	streamer, canStream := executor.(StreamingCommandExecutor)
+
+	var result RepoResult
+	repoPath, cloneURL := cloneTarget(dir, repo)
+
+	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
+		// repo doesn't exist, clone it
+		result.Action = ActionClone
+		// git picks the transport from the URL
+		cloneCmd := []string{"git", "clone"}
+		if repo.Branch != "" {
+			cloneCmd = append(cloneCmd, "--branch", repo.Branch)
+		}
+		if canStream {
+			cloneCmd = append(cloneCmd, "--progress", cloneURL, repoPath)
+			result.Output, err = streamer.ExecuteCommandStream("", onLine, cloneCmd[0], cloneCmd[1:]...)
+		} else {
+			cloneCmd = append(cloneCmd, cloneURL, repoPath)
+			result.Output, err = executor.ExecuteCommand(cloneCmd[0], cloneCmd[1:]...)
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("error cloning repo: %w", err)
+		}
+	} else if err != nil {
+		result.Err = fmt.Errorf("error checking if repo exists: %w", err)
+	} else {
+		// repo exists, pull latest changes
+		result.Action = ActionPull
+		if canStream {
+			result.Output, err = streamer.ExecuteCommandStream(repoPath, onLine, "git", "pull", "--progress")
+		} else {
+			result.Output, err = executor.ExecuteCommandInDir(repoPath, "git", "pull")
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("error pulling latest changes: %w", err)
+		}
+	}
+
+	result.Duration = time.Since(start)
+	return result
+}
+
+// Returns where a repo is cloned to within dir and the URL it's cloned from,
+// gists live in their own subtree and repos cloned by owner in their owner's
+func cloneTarget(dir string, repo Repo) (repoPath, cloneURL string) {
+	if repo.Gist {
+		cloneURL = repo.URL
+		if repo.GitPullURL != "" {
+			cloneURL = repo.GitPullURL
+		}
+		return path.Join(dir, gistsDir, repo.Name), cloneURL
 	}
-	return u.Scheme == "ssh"
+	return path.Join(dir, repo.displayName()), repo.URL
 }
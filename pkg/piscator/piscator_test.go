@@ -9,7 +9,6 @@ import (
 	"os"
 	"reflect"
 	"strconv"
-	"strings"
 	"testing"
 	"time"
 )
@@ -58,7 +57,6 @@ func TestGetRepos(t *testing.T) {
 		isSelf         bool
 		isOrg          bool
 		isForked       bool
-		makeFile       bool
 		httpError      error
 		httpStatus     int
 		httpBody       string
@@ -75,7 +73,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -92,7 +89,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -109,7 +105,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -126,7 +121,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       true,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -143,7 +137,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -160,7 +153,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -177,7 +169,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -194,7 +185,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          false,
 			isForked:       true,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -211,7 +201,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -228,7 +217,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -245,7 +233,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -262,7 +249,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -279,7 +265,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -296,7 +281,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -313,7 +297,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -330,7 +313,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         true,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -347,7 +329,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -364,7 +345,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -381,7 +361,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -398,7 +377,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       true,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -415,7 +393,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -432,7 +409,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -449,7 +425,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -466,7 +441,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -483,7 +457,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -500,7 +473,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -517,7 +489,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -534,7 +505,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -551,7 +521,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -568,7 +537,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "[]",
@@ -585,7 +553,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       true,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody: `[
@@ -627,7 +594,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          true,
 			isForked:       false,
-			makeFile:       true,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody: `[
@@ -669,7 +635,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      errors.New("network error"),
 			httpStatus:     200,
 			httpBody:       "",
@@ -686,7 +651,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     404,
 			httpBody:       "",
@@ -703,7 +667,6 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
 			httpStatus:     200,
 			httpBody:       "{invalid json}",
@@ -720,13 +683,12 @@ func TestGetRepos(t *testing.T) {
 			isSelf:         false,
 			isOrg:          false,
 			isForked:       false,
-			makeFile:       false,
 			httpError:      nil,
-			httpStatus:     200,
-			httpBody:       "[]",
+			httpStatus:     403,
+			httpBody:       `{"message": "API rate limit exceeded"}`,
 			rateLimit:      "0",
 			rateReset:      strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10),
-			wantError:      false,
+			wantError:      true,
 		},
 	}
 
@@ -745,13 +707,20 @@ func TestGetRepos(t *testing.T) {
 
 			sleeper := &MockSleeper{}
 
-			_, err := GetRepos(client, sleeper, tt.name, tt.token, tt.username, tt.password, tt.enterpriseHost, tt.isSelf, tt.isOrg, tt.isForked, tt.makeFile)
+			var rateLimited []RateLimited
+			observer := ObserverFunc(func(event Event) {
+				if e, ok := event.(RateLimited); ok {
+					rateLimited = append(rateLimited, e)
+				}
+			})
+
+			_, err := GetRepos(client, sleeper, observer, tt.name, tt.token, tt.username, tt.password, tt.enterpriseHost, tt.isSelf, tt.isOrg, tt.isForked, ListOptions{})
 			if (err != nil) != tt.wantError {
 				t.Errorf("GetRepos() error = %v, wantError %v", err, tt.wantError)
 			}
 
 			if tt.name == "forked repos" {
-				filteredJSON, err := GetRepos(client, sleeper, tt.name, tt.token, tt.username, tt.password, tt.enterpriseHost, tt.isSelf, tt.isOrg, tt.isForked, tt.makeFile)
+				filteredJSON, err := GetRepos(client, sleeper, nil, tt.name, tt.token, tt.username, tt.password, tt.enterpriseHost, tt.isSelf, tt.isOrg, tt.isForked, ListOptions{})
 				if (err != nil) != tt.wantError {
 					t.Errorf("GetRepos() error = %v, wantError %v", err, tt.wantError)
 				}
@@ -780,8 +749,11 @@ func TestGetRepos(t *testing.T) {
 
 			// If this is the rate limited test case, check that the correct sleep duration was used
 			if tt.name == "rate limited" {
-				if len(sleeper.Durations) != 3 {
-					t.Errorf("Expected 3 sleeper calls, got %v", len(sleeper.Durations))
+				if len(rateLimited) != DefaultMaxAttempts-1 {
+					t.Errorf("Expected %d RateLimited events, got %v", DefaultMaxAttempts-1, len(rateLimited))
+				}
+				if len(sleeper.Durations) != DefaultMaxAttempts-1 {
+					t.Errorf("Expected %d sleeper calls, got %v", DefaultMaxAttempts-1, len(sleeper.Durations))
 				} else {
 					// Parse reset time from the test case
 					resetTimeUnix, _ := strconv.ParseInt(tt.rateReset, 10, 64)
@@ -793,20 +765,138 @@ func TestGetRepos(t *testing.T) {
 					}
 				}
 			}
+		})
+	}
+}
 
-			// test cleanup, after it has run
-			defer func() {
-				if strings.Contains(tt.name, "-makefile") {
-					err := os.Remove("repos.json")
-					if err != nil {
-						t.Errorf("Failed to remove repos.json file: %v", err)
-					}
+func TestGetReposURL(t *testing.T) {
+	tests := []struct {
+		name           string
+		enterpriseHost string
+		isSelf         bool
+		isOrg          bool
+		expected       string
+	}{
+		{"user", "", false, false, "https://api.github.com/users/octocat/repos?per_page=100"},
+		{"org", "", false, true, "https://api.github.com/orgs/octocat/repos?per_page=100"},
+		{"self", "", true, false, "https://api.github.com/user/repos?per_page=100"},
+		{"enterprise user", "ghe.example.com", false, false, "https://ghe.example.com/api/v3/users/octocat/repos?per_page=100"},
+		{"enterprise org", "https://ghe.example.com", false, true, "https://ghe.example.com/api/v3/orgs/octocat/repos?per_page=100"},
+		{"enterprise self", "ghe.example.com", true, false, "https://ghe.example.com/api/v3/user/repos?per_page=100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &SequenceHttpClient{responses: []mockResponse{{status: 200, body: "[]"}}}
+			_, err := GetRepos(client, &MockSleeper{}, nil, "octocat", "", "tester_mctesterson", "hunter2", tt.enterpriseHost, tt.isSelf, tt.isOrg, false, ListOptions{})
+			if err != nil {
+				t.Fatalf("GetRepos() error = %v", err)
+			}
+
+			req := client.requests[0]
+			if got := req.URL.String(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+			// basic auth goes in a header for every mode, never the URL
+			if user, pass, ok := req.BasicAuth(); !ok || user != "tester_mctesterson" || pass != "hunter2" || req.URL.User != nil {
+				t.Errorf("Expected basic auth in the header, got %q %q %v (%v)", user, pass, ok, req.URL.User)
+			}
+		})
+	}
+}
+
+func TestGetReposListOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		isSelf   bool
+		isOrg    bool
+		opts     ListOptions
+		expected string
+		wantErr  bool
+	}{
+		{"team", false, true, ListOptions{Team: "platform"}, "https://api.github.com/orgs/acme/teams/platform/repos?per_page=100", false},
+		{"affiliation", true, false, ListOptions{Affiliation: []string{"owner", "collaborator"}, Visibility: "private"}, "https://api.github.com/user/repos?affiliation=owner%2Ccollaborator&per_page=100&visibility=private", false},
+		{"type", false, true, ListOptions{Type: "sources"}, "https://api.github.com/orgs/acme/repos?per_page=100&type=sources", false},
+		{"team for a user", false, false, ListOptions{Team: "platform"}, "", true},
+		{"team with type", false, true, ListOptions{Team: "platform", Type: "forks"}, "", true},
+		{"affiliation for an org", false, true, ListOptions{Affiliation: []string{"owner"}}, "", true},
+		{"invalid affiliation", true, false, ListOptions{Affiliation: []string{"friend"}}, "", true},
+		{"invalid visibility", true, false, ListOptions{Visibility: "secret"}, "", true},
+		{"invalid type", false, true, ListOptions{Type: "everything"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &SequenceHttpClient{responses: []mockResponse{{status: 200, body: "[]"}}}
+			_, err := GetRepos(client, &MockSleeper{}, nil, "acme", "token", "", "", "", tt.isSelf, tt.isOrg, false, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetRepos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(client.requests) != 0 {
+					t.Errorf("Expected no request for invalid options")
 				}
-			}()
+				return
+			}
+			if got := client.requests[0].URL.String(); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
 		})
 	}
 }
 
+func TestGetReposPagination(t *testing.T) {
+	next := `<https://api.github.com/organizations/1/repos?per_page=100&type=forks&page=2>; rel="next", <https://api.github.com/organizations/1/repos?per_page=100&type=forks&page=2>; rel="last"`
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, body: `[{"name": "repo1", "html_url": "https://github.com/acme/repo1", "fork": true}]`, headers: map[string]string{"Link": next}},
+		{status: 200, body: `[{"name": "repo2", "html_url": "https://github.com/acme/repo2", "fork": true}]`},
+	}}
+
+	var pages []ListPageFetched
+	observer := ObserverFunc(func(event Event) {
+		if e, ok := event.(ListPageFetched); ok {
+			pages = append(pages, e)
+		}
+	})
+
+	// forks are kept when they're what was asked for
+	res, err := GetRepos(client, &MockSleeper{}, observer, "acme", "token", "", "", "", false, true, false, ListOptions{Type: "forks"})
+	if err != nil {
+		t.Fatalf("GetRepos() error = %v", err)
+	}
+
+	var repos []RepoModel
+	json.Unmarshal([]byte(res), &repos)
+	if len(repos) != 2 || repos[1].Name != "repo2" {
+		t.Errorf("Expected both pages of forks, got %+v", repos)
+	}
+	if got := client.requests[1].URL.String(); got != "https://api.github.com/organizations/1/repos?per_page=100&type=forks&page=2" {
+		t.Errorf("Expected the next page to be requested, got %s", got)
+	}
+	if client.requests[1].Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected every page to be authenticated")
+	}
+	if len(pages) != 2 || pages[1].Page != 2 || pages[1].Count != 1 {
+		t.Errorf("Expected two page events, got %+v", pages)
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		link     string
+		expected string
+	}{
+		{"", ""},
+		{`<https://api.github.com/x?page=2>; rel="next", <https://api.github.com/x?page=5>; rel="last"`, "https://api.github.com/x?page=2"},
+		{`<https://api.github.com/x?page=1>; rel="prev", <https://api.github.com/x?page=1>; rel="first"`, ""},
+	}
+	for _, tt := range tests {
+		if got := nextPageURL(tt.link); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
 func TestRepoByLanguage(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -1031,7 +1121,7 @@ func TestCloneReposFromJson(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := CloneReposFromJson(tt.executor, tt.jsonStr, tt.name, tt.concurrentLimit, tt.verboseLog)
+			err := CloneReposFromJson(tt.executor, NewProgress(io.Discard), tt.jsonStr, tt.name, tt.concurrentLimit)
 			if (err != nil) != tt.wantError {
 				t.Errorf("CloneReposFromJson() error = %v, wantError %v", err, tt.wantError)
 			}
@@ -1047,36 +1137,3 @@ func TestCloneReposFromJson(t *testing.T) {
 
 	}
 }
-
-func TestIsSSHURL(t *testing.T) {
-	tests := []struct {
-		name     string
-		url      string
-		expected bool
-	}{
-		{
-			name:     "SSH URL",
-			url:      "ssh://github.com/user/repo",
-			expected: true,
-		},
-		{
-			name:     "HTTP URL",
-			url:      "http://github.com/user/repo",
-			expected: false,
-		},
-		{
-			name:     "Invalid URL",
-			url:      "invalid-url",
-			expected: false,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got := isSSHURL(tt.url)
-			if got != tt.expected {
-				t.Errorf("Expected %v, got %v", tt.expected, got)
-			}
-		})
-	}
-}
@@ -0,0 +1,291 @@
+package piscator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// width of the overall progress bar in characters
+const progressBarWidth = 30
+
+// git phases reported while cloning or pulling
+const (
+	PhaseStarting    = "starting"
+	PhaseCounting    = "counting"
+	PhaseCompressing = "compressing"
+	PhaseReceiving   = "receiving"
+	PhaseResolving   = "resolving"
+	PhaseCheckout    = "checkout"
+)
+
+var gitPhases = map[string]string{
+	"enumerating objects": PhaseCounting,
+	"counting objects":    PhaseCounting,
+	"compressing objects": PhaseCompressing,
+	"receiving objects":   PhaseReceiving,
+	"resolving deltas":    PhaseResolving,
+	"updating files":      PhaseCheckout,
+}
+
+var gitProgressRe = regexp.MustCompile(`^(?:remote:\s*)?([A-Za-z ]+):\s+(\d+)%`)
+
+// Parses a line of `git --progress` output such as
+// "Receiving objects:  45% (450/1000), 1.20 MiB | 1.10 MiB/s" into its phase
+// and percentage.
+func parseGitProgress(line string) (phase string, percent int, ok bool) {
+	m := gitProgressRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return "", 0, false
+	}
+	phase, ok = gitPhases[strings.ToLower(strings.TrimSpace(m[1]))]
+	if !ok {
+		return "", 0, false
+	}
+	percent, _ = strconv.Atoi(m[2])
+	return phase, percent, true
+}
+
+type repoProgress struct {
+	phase   string
+	percent int
+}
+
+// Progress renders a reel on a terminal, redrawing one line per active clone
+// plus an overall bar with throughput and ETA. When out isn't a terminal it
+// draws nothing and a LogObserver is expected to report progress instead.
+//
+// Progress is also an io.Writer, so log output written through it is printed
+// above the view rather than tearing through it.
+type Progress struct {
+	out io.Writer
+	tty bool
+	// columns returns the terminal width, lines wider than it would wrap and
+	// throw off the redraw
+	columns func() int
+
+	mu     sync.Mutex
+	total  int
+	start  time.Time
+	active map[string]*repoProgress
+	done   int
+	failed int
+	drawn  int // lines drawn by the previous render
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// Creates a Progress writing to out, the view is only drawn when out is a
+// terminal.
+func NewProgress(out io.Writer) *Progress {
+	return &Progress{
+		out:     out,
+		tty:     isTerminal(out),
+		columns: func() int { return terminalWidth(out) },
+		active:  map[string]*repoProgress{},
+	}
+}
+
+// Returns the width of the terminal w writes to, or 0 when it's unknown
+func terminalWidth(w io.Writer) int {
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	width, _, err := term.GetSize(int(f.Fd()))
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func (p *Progress) Observe(event Event) {
+	switch e := event.(type) {
+	case ReelStarted:
+		p.reelStarted(e.Total)
+	case RepoStarted:
+		p.repoStarted(e.Repo.displayName())
+	case RepoProgress:
+		p.repoPhase(e.Repo.displayName(), e.Phase, e.Percent)
+	case RepoFinished:
+		p.repoFinished(e.Repo.displayName(), e.Result)
+	case ReelFinished:
+		p.reelFinished()
+	}
+}
+
+// Writes b to out, above the progress view while it is drawn.
+func (p *Progress) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.drawn == 0 {
+		return p.out.Write(b)
+	}
+	p.clear()
+	n, err := p.out.Write(b)
+	p.render()
+	return n, err
+}
+
+// on a terminal the view is redrawn periodically until the reel finishes
+func (p *Progress) reelStarted(total int) {
+	p.mu.Lock()
+	p.total, p.done, p.failed = total, 0, 0
+	p.start = time.Now()
+	p.mu.Unlock()
+
+	if !p.tty {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.stopped.Add(1)
+	go func(stop chan struct{}) {
+		defer p.stopped.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.mu.Lock()
+				p.render()
+				p.mu.Unlock()
+			case <-stop:
+				return
+			}
+		}
+	}(p.stop)
+}
+
+// stops rendering and leaves the final bar on screen
+func (p *Progress) reelFinished() {
+	if p.stop == nil {
+		return
+	}
+	close(p.stop)
+	p.stopped.Wait()
+	p.stop = nil
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.render()
+	p.drawn = 0
+}
+
+func (p *Progress) repoStarted(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active[name] = &repoProgress{phase: PhaseStarting}
+}
+
+func (p *Progress) repoPhase(name, phase string, percent int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if repo, ok := p.active[name]; ok {
+		repo.phase, repo.percent = phase, percent
+	}
+}
+
+func (p *Progress) repoFinished(name string, result RepoResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.active, name)
+	p.done++
+	if result.Err != nil {
+		p.failed++
+	}
+}
+
+// moves the cursor back over the previous render, callers must hold mu
+func (p *Progress) clear() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\x1b[%dA\x1b[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+// redraws the view in place, callers must hold mu
+func (p *Progress) render() {
+	lines := p.lines(time.Now())
+	// the cursor moves back by lines, so none may wrap onto a second row
+	if width := p.columns(); width > 1 {
+		for i, line := range lines {
+			lines[i] = truncate(line, width-1)
+		}
+	}
+
+	var b strings.Builder
+	if p.drawn > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA", p.drawn)
+	}
+	for _, line := range lines {
+		b.WriteString("\x1b[2K")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	b.WriteString("\x1b[J")
+	io.WriteString(p.out, b.String())
+	p.drawn = len(lines)
+}
+
+// builds the lines of the view, callers must hold mu
+func (p *Progress) lines(now time.Time) []string {
+	names := make([]string, 0, len(p.active))
+	for name := range p.active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		repo := p.active[name]
+		lines = append(lines, fmt.Sprintf("  %-40s %-12s %3d%%", truncate(name, 40), repo.phase, repo.percent))
+	}
+
+	return append(lines, p.summary(now))
+}
+
+// overall bar with throughput and ETA, callers must hold mu
+func (p *Progress) summary(now time.Time) string {
+	ratio := 1.0
+	if p.total > 0 {
+		ratio = float64(p.done) / float64(p.total)
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("#", filled) + strings.Repeat("-", progressBarWidth-filled)
+
+	elapsed := now.Sub(p.start)
+	rate := 0.0
+	if elapsed > 0 {
+		rate = float64(p.done) / elapsed.Seconds()
+	}
+
+	eta := "--"
+	if rate > 0 {
+		remaining := time.Duration(float64(p.total-p.done) / rate * float64(time.Second))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	line := fmt.Sprintf("[%s] %d/%d %3.0f%%  %.1f repos/s  ETA %s", bar, p.done, p.total, ratio*100, rate, eta)
+	if p.failed > 0 {
+		line += fmt.Sprintf("  %d failed", p.failed)
+	}
+	return line
+}
@@ -0,0 +1,191 @@
+package piscator
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseGitProgress(t *testing.T) {
+	tests := []struct {
+		line    string
+		phase   string
+		percent int
+		ok      bool
+	}{
+		{"remote: Counting objects:  45% (45/100)", PhaseCounting, 45, true},
+		{"remote: Enumerating objects: 100% (12/12), done.", PhaseCounting, 100, true},
+		{"remote: Compressing objects:   3% (1/30)", PhaseCompressing, 3, true},
+		{"Receiving objects:  12% (120/1000), 1.00 MiB | 2.00 MiB/s", PhaseReceiving, 12, true},
+		{"Resolving deltas: 100% (80/80), done.", PhaseResolving, 100, true},
+		{"Updating files:  50% (5/10)", PhaseCheckout, 50, true},
+		{"Cloning into 'repo1'...", "", 0, false},
+		{"remote: Total 12 (delta 0), reused 0 (delta 0)", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		phase, percent, ok := parseGitProgress(tt.line)
+		if phase != tt.phase || percent != tt.percent || ok != tt.ok {
+			t.Errorf("parseGitProgress(%q) = %q, %d, %v, expected %q, %d, %v", tt.line, phase, percent, ok, tt.phase, tt.percent, tt.ok)
+		}
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	w := &lineWriter{onLine: func(line string) { lines = append(lines, line) }}
+
+	w.Write([]byte("Cloning into 'repo1'...\nReceiving objects:  10%\rReceiving "))
+	w.Write([]byte("objects: 100%\n"))
+	w.Write([]byte("trailing"))
+	w.flush()
+
+	expected := []string{"Cloning into 'repo1'...", "Receiving objects:  10%", "Receiving objects: 100%", "trailing"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Expected %v, got %v", expected, lines)
+	}
+	if !strings.HasPrefix(w.out.String(), "Cloning into") {
+		t.Errorf("Expected the full output to be collected, got %q", w.out.String())
+	}
+}
+
+func TestProgressNotTerminal(t *testing.T) {
+	var out bytes.Buffer
+	progress := NewProgress(&out)
+
+	repo1 := Repo{Name: "repo1"}
+	progress.Observe(ReelStarted{Dir: "shimman-dev", Total: 1})
+	progress.Observe(RepoStarted{Repo: repo1})
+	progress.Observe(RepoProgress{Repo: repo1, Phase: PhaseReceiving, Percent: 50})
+	progress.Observe(RepoFinished{Repo: repo1, Result: RepoResult{Action: ActionClone}})
+	progress.Observe(ReelFinished{Total: 1})
+
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing to be drawn when not writing to a terminal, got %q", out.String())
+	}
+	if progress.done != 1 {
+		t.Errorf("Expected 1 repo to be done, got %d", progress.done)
+	}
+
+	// log output passes straight through when the view isn't drawn
+	progress.Write([]byte("hello\n"))
+	if out.String() != "hello\n" {
+		t.Errorf("Expected writes to pass through, got %q", out.String())
+	}
+}
+
+func TestProgressLines(t *testing.T) {
+	progress := NewProgress(&bytes.Buffer{})
+	progress.total = 4
+	progress.start = time.Now().Add(-10 * time.Second)
+	progress.active["repo2"] = &repoProgress{phase: PhaseResolving, percent: 90}
+	progress.active["repo1"] = &repoProgress{phase: PhaseReceiving, percent: 45}
+	progress.done = 2
+
+	lines := progress.lines(progress.start.Add(10 * time.Second))
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %v", len(lines), lines)
+	}
+	if !strings.Contains(lines[0], "repo1") || !strings.Contains(lines[0], "receiving") || !strings.Contains(lines[0], "45%") {
+		t.Errorf("Expected the first worker line to show repo1, got %q", lines[0])
+	}
+	for _, want := range []string{"2/4", " 50%", "0.2 repos/s", "ETA 10s"} {
+		if !strings.Contains(lines[2], want) {
+			t.Errorf("Expected summary to contain %q, got %q", want, lines[2])
+		}
+	}
+}
+
+func TestProgressRenderTruncatesToWidth(t *testing.T) {
+	var out bytes.Buffer
+	progress := NewProgress(&out)
+	progress.columns = func() int { return 30 }
+	progress.total = 2
+	progress.start = time.Now()
+	progress.active["a-repo-with-a-name-far-wider-than-the-terminal"] = &repoProgress{phase: PhaseReceiving, percent: 10}
+
+	progress.render()
+	progress.render()
+
+	if progress.drawn != 2 {
+		t.Errorf("Expected 2 lines drawn, got %d", progress.drawn)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n") {
+		// the escape sequences take no columns
+		visible := line[strings.LastIndex(line, "[2K")+len("[2K"):]
+		if n := len([]rune(visible)); n >= 30 {
+			t.Errorf("Expected lines narrower than the terminal, got %d columns in %q", n, visible)
+		}
+	}
+	if !strings.Contains(out.String(), "\x1b[2A") {
+		t.Errorf("Expected the redraw to move up the 2 lines drawn, got %q", out.String())
+	}
+}
+
+type MockStreamingExecutor struct {
+	MockCommandExecutor
+	lines []string
+	args  [][]string
+}
+
+func (m *MockStreamingExecutor) ExecuteCommandStream(dir string, onLine func(line string), name string, arg ...string) ([]byte, error) {
+	m.args = append(m.args, arg)
+	for _, line := range m.lines {
+		onLine(line)
+	}
+	return []byte("ok"), nil
+}
+
+func TestCloneReposFromJsonStreaming(t *testing.T) {
+	dir := t.TempDir()
+	executor := &MockStreamingExecutor{lines: []string{"Receiving objects:  50% (1/2)"}}
+
+	var mu sync.Mutex
+	var events []Event
+	observer := ObserverFunc(func(event Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+
+	err := CloneReposFromJson(executor, observer, `[{ "name": "repo1", "html_url": "url1" }]`, dir, 2)
+	if err != nil {
+		t.Fatalf("CloneReposFromJson() error = %v", err)
+	}
+
+	if len(executor.args) != 1 || executor.args[0][1] != "--progress" {
+		t.Errorf("Expected a single clone with --progress, got %v", executor.args)
+	}
+
+	repo := Repo{Name: "repo1", URL: "url1"}
+	expected := []Event{
+		ReelStarted{Dir: dir, Total: 1},
+		RepoStarted{Repo: repo},
+		RepoProgress{Repo: repo, Phase: PhaseReceiving, Percent: 50},
+	}
+	if len(events) != 5 || !reflect.DeepEqual(events[:3], expected) {
+		t.Fatalf("Expected events to start with %v, got %v", expected, events)
+	}
+	finished, ok := events[3].(RepoFinished)
+	if !ok || finished.Result.Action != ActionClone || finished.Result.Err != nil {
+		t.Errorf("Expected a successful clone, got %v", events[3])
+	}
+	if events[4] != (ReelFinished{Total: 1}) {
+		t.Errorf("Expected ReelFinished, got %v", events[4])
+	}
+}
+
+func TestChannelObserver(t *testing.T) {
+	ch := make(chan Event, 1)
+	ChannelObserver(ch).Observe(ListPageFetched{Page: 1})
+
+	if got := <-ch; got != (ListPageFetched{Page: 1}) {
+		t.Errorf("Expected the event to be sent on the channel, got %v", got)
+	}
+
+	// a nil observer ignores events
+	notify(nil, ListPageFetched{Page: 1})
+}
@@ -0,0 +1,58 @@
+package piscator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RepoFileMetadata records how a repos file was made, so it can be told
+// apart from others and fed back into reel
+type RepoFileMetadata struct {
+	// Source is what was listed, such as org:acme or search:topic:cli
+	Source    string    `json:"source" yaml:"source"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	Version   string    `json:"piscator_version" yaml:"piscator_version"`
+	// Filters are the flags that narrowed the listing, by name
+	Filters map[string]string `json:"filters,omitempty" yaml:"filters,omitempty"`
+}
+
+// repoFile is the shape of json and yaml output with metadata
+type repoFile struct {
+	Metadata *RepoFileMetadata `json:"metadata" yaml:"metadata"`
+	Repos    []repoRecord      `json:"repos" yaml:"repos"`
+}
+
+// Formats the metadata as # comment lines
+func (m RepoFileMetadata) comment() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# source: %s\n", m.Source)
+	fmt.Fprintf(&b, "# created_at: %s\n", m.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "# piscator_version: %s\n", m.Version)
+	if len(m.Filters) > 0 {
+		keys := make([]string, 0, len(m.Filters))
+		for key := range m.Filters {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		filters := make([]string, len(keys))
+		for i, key := range keys {
+			filters[i] = key + "=" + m.Filters[key]
+		}
+		fmt.Fprintf(&b, "# filters: %s\n", strings.Join(filters, " "))
+	}
+	return b.String()
+}
+
+// Writes a file with the output of write, through a temporary file renamed
+// into place so an existing file is only replaced once the output is complete
+func WriteFileAtomic(name string, write func(w io.Writer) error) error {
+	var b bytes.Buffer
+	if err := write(&b); err != nil {
+		return err
+	}
+	return writeFileAtomic(name, b.Bytes(), 0644)
+}
@@ -0,0 +1,125 @@
+package piscator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMetadata = RepoFileMetadata{
+	Source:    "org:shimman-dev",
+	CreatedAt: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	Version:   "v1.2.3",
+	Filters:   map[string]string{"language": "go", "forked": "true"},
+}
+
+func TestWriteReposWithMetadata(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"json", []string{`"metadata": {`, `"source": "org:shimman-dev"`, `"created_at": "2024-05-01T12:00:00Z"`, `"piscator_version": "v1.2.3"`, `"repos": [`}},
+		{"yaml", []string{"metadata:\n  source: org:shimman-dev", "repos:\n  - name: piscator"}},
+		{"csv", []string{"# source: org:shimman-dev\n# created_at: 2024-05-01T12:00:00Z\n# piscator_version: v1.2.3\n# filters: forked=true language=go\nname,html_url"}},
+		{"urls", []string{"# filters: forked=true language=go\nhttps://github.com/shimman-dev/piscator\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteReposWithMetadata(&b, formatRepos, tt.format, nil, testMetadata); err != nil {
+				t.Fatalf("WriteReposWithMetadata() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("Expected %q in\n%s", want, b.String())
+				}
+			}
+		})
+	}
+
+	if err := WriteReposWithMetadata(io.Discard, formatRepos, "jsonl", nil, testMetadata); err == nil {
+		t.Errorf("Expected an error for jsonl with metadata")
+	}
+}
+
+func TestRepoFileRoundTrip(t *testing.T) {
+	// reel reads back every format cast writes, with and without metadata
+	for _, format := range OutputFormats {
+		for _, withMetadata := range []bool{false, true} {
+			// jsonl has no room for metadata
+			if format == "jsonl" && withMetadata {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s metadata=%v", format, withMetadata), func(t *testing.T) {
+				var b bytes.Buffer
+				var err error
+				if withMetadata {
+					err = WriteReposWithMetadata(&b, formatRepos, format, nil, testMetadata)
+				} else {
+					err = WriteRepos(&b, formatRepos, format, nil)
+				}
+				if err != nil {
+					t.Fatalf("WriteRepos() error = %v", err)
+				}
+				repos, err := ReadRepoList(&b)
+				if err != nil {
+					t.Fatalf("ReadRepoList() error = %v", err)
+				}
+				var got []string
+				for _, repo := range repos {
+					got = append(got, repo.Name+" "+repo.URL)
+				}
+				want := []string{"piscator https://github.com/shimman-dev/piscator", "notes, misc https://github.com/shimman-dev/notes"}
+				// a URL list only has the names the URLs give
+				if format == "urls" {
+					want[1] = "notes https://github.com/shimman-dev/notes"
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Expected %v, got %v", want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "repos.json")
+	if err := os.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed write leaves the old file alone
+	err := WriteFileAtomic(name, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return errors.New("listing failed")
+	})
+	if err == nil {
+		t.Fatalf("Expected the write error")
+	}
+	if data, _ := os.ReadFile(name); string(data) != "old" {
+		t.Errorf("Expected the old file to be kept, got %q", data)
+	}
+
+	if err := WriteFileAtomic(name, func(w io.Writer) error {
+		_, err := io.WriteString(w, "new")
+		return err
+	}); err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+	if data, _ := os.ReadFile(name); string(data) != "new" {
+		t.Errorf("Expected the new file, got %q", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(name))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,148 @@
+package piscator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the search API stops returning results after the first 1000 matches
+const searchResultCap = 1000
+
+// no repository on GitHub was created before this, the start of the first
+// created: slice
+var searchEpoch = time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type searchResponse struct {
+	TotalCount int         `json:"total_count"`
+	Items      []RepoModel `json:"items"`
+}
+
+// searcher runs a repository search, slicing the query by creation date so
+// every slice stays under the result cap
+type searcher struct {
+	rc       *RetryClient
+	observer Observer
+	endpoint string
+	token    string
+	pageSize int
+	cap      int
+	now      func() time.Time
+
+	page  int
+	seen  map[int64]bool
+	repos []RepoModel
+}
+
+// Retrieves every repository matching a GitHub search query such as
+// "org:shimman-dev topic:cli" or "language:go pushed:>2024-01-01", producing
+// the same output as GetRepos. Results span owners, so each is cloned into an
+// owner/name directory.
+//
+// Queries matching more than the 1000 results search returns are split into
+// created: date ranges and run slice by slice, so they shouldn't contain a
+// created: qualifier themselves. Search has its own, much lower, rate limit
+// so it never shares a budget with other listings.
+func SearchRepos(client HttpClient, sleeper Sleeper, observer Observer, query, token, enterpriseHost string, isForked bool) (string, error) {
+	if strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("empty search query")
+	}
+
+	// search is limited separately from the core API
+	if rc, ok := client.(*RetryClient); ok {
+		client = rc.Client
+	}
+
+	s := &searcher{
+		rc:       NewRetryClient(client, sleeper, observer),
+		observer: observer,
+		endpoint: APIBaseURL(enterpriseHost) + "/search/repositories",
+		token:    token,
+		pageSize: restPageSize,
+		cap:      searchResultCap,
+		now:      time.Now,
+		seen:     map[int64]bool{},
+	}
+	if err := s.search(query, time.Time{}, time.Time{}); err != nil {
+		return "", err
+	}
+
+	return finishRepos(s.repos, isForked)
+}
+
+// Searches query within the created range from..to, a zero range is
+// unbounded. When there are more results than the cap the range is halved
+// and both halves are searched instead.
+func (s *searcher) search(query string, from, to time.Time) error {
+	q := query
+	if !from.IsZero() {
+		q += fmt.Sprintf(" created:%s..%s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+
+	pageURL := s.endpoint + "?" + url.Values{
+		"q":        {q},
+		"per_page": {strconv.Itoa(s.pageSize)},
+	}.Encode()
+
+	for first := true; pageURL != ""; first = false {
+		res, next, err := s.fetch(pageURL)
+		if err != nil {
+			return err
+		}
+
+		if first && res.TotalCount > s.cap {
+			if from.IsZero() {
+				from, to = searchEpoch, s.now().UTC().Truncate(time.Second)
+			}
+			// a single second can't be split further, keep what search returns
+			if to.Sub(from) >= 2*time.Second {
+				mid := from.Add(to.Sub(from) / 2).Truncate(time.Second)
+				if err := s.search(query, from, mid); err != nil {
+					return err
+				}
+				return s.search(query, mid.Add(time.Second), to)
+			}
+		}
+
+		for _, repo := range res.Items {
+			if !s.seen[repo.ID] {
+				s.seen[repo.ID] = true
+				repo.ByOwner = true
+				s.repos = append(s.repos, repo)
+			}
+		}
+		pageURL = next
+	}
+	return nil
+}
+
+func (s *searcher) fetch(pageURL string) (*searchResponse, string, error) {
+	req, err := http.NewRequest("GET", pageURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", "shimman-dev/piscator")
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	res, err := s.rc.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	var body searchResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		return nil, "", err
+	}
+	s.page++
+	notify(s.observer, ListPageFetched{URL: pageURL, Page: s.page, Count: len(body.Items)})
+
+	return &body, nextPageURL(res.Header.Get("Link")), nil
+}
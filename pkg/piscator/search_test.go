@@ -0,0 +1,165 @@
+package piscator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// HandlerHttpClient serves requests with an http.Handler instead of the network
+type HandlerHttpClient struct {
+	handler  http.Handler
+	requests []*http.Request
+}
+
+func (c *HandlerHttpClient) Do(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req)
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	return rec.Result(), nil
+}
+
+type searchRepo struct {
+	RepoModel
+	created time.Time
+}
+
+// Answers searches over repos the way GitHub does: filtered by the created:
+// range, paginated with Link headers and capped at resultCap results
+func newSearchHandler(t *testing.T, repos []searchRepo, pageSize, resultCap int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		if !strings.HasPrefix(q, "topic:cli") {
+			t.Errorf("Expected the query to be kept, got %q", q)
+		}
+
+		var matches []RepoModel
+		from, to := time.Time{}, time.Now()
+		if i := strings.Index(q, "created:"); i >= 0 {
+			bounds := strings.Split(q[i+len("created:"):], "..")
+			from, _ = time.Parse(time.RFC3339, bounds[0])
+			to, _ = time.Parse(time.RFC3339, bounds[1])
+		}
+		for _, repo := range repos {
+			if !repo.created.Before(from) && !repo.created.After(to) {
+				matches = append(matches, repo.RepoModel)
+			}
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		start, end := (page-1)*pageSize, page*pageSize
+		if end > len(matches) {
+			end = len(matches)
+		}
+		if end < len(matches) && end < resultCap {
+			next := *r.URL
+			values := next.Query()
+			values.Set("page", strconv.Itoa(page+1))
+			next.RawQuery = values.Encode()
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+		}
+		if start > end {
+			start = end
+		}
+		json.NewEncoder(w).Encode(searchResponse{TotalCount: len(matches), Items: matches[start:end]})
+	})
+}
+
+func TestSearchRepos(t *testing.T) {
+	var repos []searchRepo
+	start := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 25; i++ {
+		repos = append(repos, searchRepo{
+			RepoModel: RepoModel{Repo: Repo{Name: fmt.Sprintf("repo%d", i), URL: fmt.Sprintf("https://github.com/acme/repo%d", i)}, ID: int64(i)},
+			created:   start.Add(time.Duration(i) * 24 * time.Hour),
+		})
+	}
+
+	tests := []struct {
+		name     string
+		cap      int
+		wantFull bool
+	}{
+		{"under the cap", 100, true},
+		{"sliced by created date", 10, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &HandlerHttpClient{handler: newSearchHandler(t, repos, 4, tt.cap)}
+			s := &searcher{
+				rc:       NewRetryClient(client, &MockSleeper{}, nil),
+				endpoint: "https://api.github.com/search/repositories",
+				pageSize: 4,
+				cap:      tt.cap,
+				now:      func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) },
+				seen:     map[int64]bool{},
+			}
+			if err := s.search("topic:cli", time.Time{}, time.Time{}); err != nil {
+				t.Fatalf("search() error = %v", err)
+			}
+
+			if len(s.repos) != len(repos) {
+				t.Errorf("Expected all %d repos, got %d", len(repos), len(s.repos))
+			}
+			seen := map[int64]bool{}
+			for _, repo := range s.repos {
+				if seen[repo.ID] {
+					t.Errorf("Expected no duplicates, got %s twice", repo.Name)
+				}
+				if !repo.ByOwner {
+					t.Errorf("Expected %s to be cloned by owner", repo.Name)
+				}
+				seen[repo.ID] = true
+			}
+		})
+	}
+}
+
+func TestSearchReposRequest(t *testing.T) {
+	client := &SequenceHttpClient{responses: []mockResponse{{status: 200, body: `{"total_count": 1, "items": [{"id": 1, "name": "piscator", "html_url": "https://github.com/shimman-dev/piscator"}]}`}}}
+
+	res, err := SearchRepos(client, &MockSleeper{}, nil, "org:shimman-dev language:go", "token", "ghe.example.com", false)
+	if err != nil {
+		t.Fatalf("SearchRepos() error = %v", err)
+	}
+	if !strings.Contains(res, `"name": "piscator"`) {
+		t.Errorf("Expected the search results, got %s", res)
+	}
+
+	req := client.requests[0]
+	if got := req.URL.String(); got != "https://ghe.example.com/api/v3/search/repositories?per_page=100&q=org%3Ashimman-dev+language%3Ago" {
+		t.Errorf("Unexpected URL %s", got)
+	}
+	if req.Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("Expected the token to be sent")
+	}
+
+	if _, err := SearchRepos(client, &MockSleeper{}, nil, " ", "", "", false); err == nil {
+		t.Errorf("Expected an error for an empty query")
+	}
+}
+
+func TestSearchReposOwnBudget(t *testing.T) {
+	// a search running out of its budget doesn't hold other listings
+	shared := NewRetryClient(&SequenceHttpClient{responses: []mockResponse{{
+		status:  200,
+		body:    `{"total_count": 0, "items": []}`,
+		headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)},
+	}}}, &MockSleeper{}, nil)
+
+	if _, err := SearchRepos(shared, &MockSleeper{}, nil, "topic:cli", "", "", false); err != nil {
+		t.Fatalf("SearchRepos() error = %v", err)
+	}
+	if wait := shared.Budget.held(time.Now()); wait != 0 {
+		t.Errorf("Expected the shared budget to be untouched, held for %v", wait)
+	}
+}
@@ -0,0 +1,130 @@
+package piscator
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// gists are cloned into this directory next to the reeled repos
+const gistsDir = "gists"
+
+type starredRepo struct {
+	StarredAt string    `json:"starred_at"`
+	Repo      RepoModel `json:"repo"`
+}
+
+type gist struct {
+	ID          string `json:"id"`
+	HTMLURL     string `json:"html_url"`
+	GitPullURL  string `json:"git_pull_url"`
+	Description string `json:"description"`
+	Public      bool   `json:"public"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// Retrieves the repositories a user starred, or the token's user when isSelf,
+// most recently starred first. Produces the same output as GetRepos with
+// starred_at set on every repo, which is cloned into an owner/name directory.
+func GetStarred(client HttpClient, sleeper Sleeper, observer Observer, name, token, enterpriseHost string, isSelf, isForked bool) (string, error) {
+	gh, err := url.Parse(APIBaseURL(enterpriseHost))
+	if err != nil {
+		return "", err
+	}
+	if isSelf {
+		gh.Path = path.Join(gh.Path, "user", "starred")
+	} else {
+		gh.Path = path.Join(gh.Path, "users", name, "starred")
+	}
+	gh.RawQuery = url.Values{
+		"per_page":  {strconv.Itoa(restPageSize)},
+		"sort":      {"created"},
+		"direction": {"desc"},
+	}.Encode()
+
+	var repos []RepoModel
+	err = fetchPages(NewRetryClient(client, sleeper, observer), observer, gh.String(), func(req *http.Request) {
+		// the star media type wraps each repo with the time it was starred
+		req.Header.Set("Accept", "application/vnd.github.star+json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}, func(body io.Reader) (int, error) {
+		var page []starredRepo
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return 0, err
+		}
+		for _, s := range page {
+			s.Repo.StarredAt = s.StarredAt
+			s.Repo.ByOwner = true
+			repos = append(repos, s.Repo)
+		}
+		return len(page), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// RFC 3339 timestamps sort as strings
+	sort.SliceStable(repos, func(i, j int) bool {
+		return repos[i].StarredAt > repos[j].StarredAt
+	})
+
+	return finishRepos(repos, isForked)
+}
+
+// Retrieves the gists of a user, or the token's user when isSelf, as repos
+// CloneReposFromJson clones into a gists/ directory.
+func GetGists(client HttpClient, sleeper Sleeper, observer Observer, name, token, enterpriseHost string, isSelf bool) (string, error) {
+	gh, err := url.Parse(APIBaseURL(enterpriseHost))
+	if err != nil {
+		return "", err
+	}
+	if isSelf {
+		gh.Path = path.Join(gh.Path, "gists")
+	} else {
+		gh.Path = path.Join(gh.Path, "users", name, "gists")
+	}
+	gh.RawQuery = url.Values{"per_page": {strconv.Itoa(restPageSize)}}.Encode()
+
+	var repos []RepoModel
+	err = fetchPages(NewRetryClient(client, sleeper, observer), observer, gh.String(), func(req *http.Request) {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	}, func(body io.Reader) (int, error) {
+		var page []gist
+		if err := json.NewDecoder(body).Decode(&page); err != nil {
+			return 0, err
+		}
+		for _, g := range page {
+			repos = append(repos, g.toRepoModel())
+		}
+		return len(page), nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return finishRepos(repos, false)
+}
+
+// Maps a gist onto RepoModel, secret gists are reported as private
+func (g gist) toRepoModel() RepoModel {
+	visibility := "public"
+	if !g.Public {
+		visibility = "secret"
+	}
+	return RepoModel{
+		Repo:        Repo{Name: g.ID, URL: g.HTMLURL, GitPullURL: g.GitPullURL, Gist: true},
+		Private:     !g.Public,
+		Visibility:  visibility,
+		PushedAt:    g.UpdatedAt,
+		Description: g.Description,
+	}
+}
@@ -0,0 +1,117 @@
+package piscator
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetStarred(t *testing.T) {
+	next := `<https://api.github.com/user/1/starred?page=2>; rel="next"`
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, headers: map[string]string{"Link": next}, body: `[
+			{"starred_at": "2023-05-01T00:00:00Z", "repo": {"name": "cobra", "html_url": "https://github.com/spf13/cobra"}},
+			{"starred_at": "2023-07-01T00:00:00Z", "repo": {"name": "viper", "html_url": "https://github.com/spf13/viper"}}
+		]`},
+		{status: 200, body: `[
+			{"starred_at": "2023-06-01T00:00:00Z", "repo": {"name": "fork", "html_url": "https://github.com/octocat/fork", "fork": true}},
+			{"starred_at": "2022-01-01T00:00:00Z", "repo": {"name": "pflag", "html_url": "https://github.com/spf13/pflag"}}
+		]`},
+	}}
+
+	res, err := GetStarred(client, &MockSleeper{}, nil, "octocat", "token", "", false, false)
+	if err != nil {
+		t.Fatalf("GetStarred() error = %v", err)
+	}
+
+	var repos []RepoModel
+	json.Unmarshal([]byte(res), &repos)
+	var names, starred []string
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+		starred = append(starred, repo.StarredAt)
+	}
+	if expected := []string{"viper", "cobra", "pflag"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v most recently starred first without forks, got %v", expected, names)
+	}
+	if starred[0] != "2023-07-01T00:00:00Z" {
+		t.Errorf("Expected starred_at to be kept, got %v", starred)
+	}
+	if !repos[0].ByOwner {
+		t.Errorf("Expected stars to be cloned by owner")
+	}
+
+	req := client.requests[0]
+	if got := req.URL.String(); got != "https://api.github.com/users/octocat/starred?direction=desc&per_page=100&sort=created" {
+		t.Errorf("Unexpected URL %s", got)
+	}
+	if req.Header.Get("Accept") != "application/vnd.github.star+json" {
+		t.Errorf("Expected the star media type, got %q", req.Header.Get("Accept"))
+	}
+	if len(client.requests) != 2 {
+		t.Errorf("Expected both pages to be fetched, got %d requests", len(client.requests))
+	}
+}
+
+func TestGetGists(t *testing.T) {
+	client := &SequenceHttpClient{responses: []mockResponse{{status: 200, body: `[
+		{"id": "aa5a315d61ae9438b18d", "html_url": "https://gist.github.com/aa5a315d61ae9438b18d", "git_pull_url": "https://gist.github.com/aa5a315d61ae9438b18d.git", "description": "Hello World Examples", "public": true, "updated_at": "2023-07-01T12:00:00Z"},
+		{"id": "bb5a315d61ae9438b18d", "html_url": "https://gist.github.com/bb5a315d61ae9438b18d", "git_pull_url": "https://gist.github.com/bb5a315d61ae9438b18d.git", "public": false}
+	]`}}}
+
+	res, err := GetGists(client, &MockSleeper{}, nil, "octocat", "", "ghe.example.com", false)
+	if err != nil {
+		t.Fatalf("GetGists() error = %v", err)
+	}
+	if got := client.requests[0].URL.String(); got != "https://ghe.example.com/api/v3/users/octocat/gists?per_page=100" {
+		t.Errorf("Unexpected URL %s", got)
+	}
+
+	var repos []RepoModel
+	json.Unmarshal([]byte(res), &repos)
+	expected := []RepoModel{
+		{
+			Repo:        Repo{Name: "aa5a315d61ae9438b18d", URL: "https://gist.github.com/aa5a315d61ae9438b18d", GitPullURL: "https://gist.github.com/aa5a315d61ae9438b18d.git", Gist: true},
+			Visibility:  "public",
+			PushedAt:    "2023-07-01T12:00:00Z",
+			Description: "Hello World Examples",
+		},
+		{
+			Repo:       Repo{Name: "bb5a315d61ae9438b18d", URL: "https://gist.github.com/bb5a315d61ae9438b18d", GitPullURL: "https://gist.github.com/bb5a315d61ae9438b18d.git", Gist: true},
+			Private:    true,
+			Visibility: "secret",
+		},
+	}
+	if !reflect.DeepEqual(repos, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, repos)
+	}
+}
+
+func TestCloneGists(t *testing.T) {
+	dir := t.TempDir()
+	executor := &MockStreamingExecutor{}
+	jsonStr := `[
+		{"name": "repo1", "html_url": "https://github.com/octocat/repo1"},
+		{"name": "aa5a315d61ae9438b18d", "html_url": "https://gist.github.com/aa5a315d61ae9438b18d", "git_pull_url": "https://gist.github.com/aa5a315d61ae9438b18d.git", "gist": true}
+	]`
+
+	if err := CloneReposFromJson(executor, NewProgress(io.Discard), jsonStr, dir, 1); err != nil {
+		t.Fatalf("CloneReposFromJson() error = %v", err)
+	}
+
+	expected := map[string]string{
+		"https://github.com/octocat/repo1":                 filepath.Join(dir, "repo1"),
+		"https://gist.github.com/aa5a315d61ae9438b18d.git": filepath.Join(dir, "gists", "aa5a315d61ae9438b18d"),
+	}
+	if len(executor.args) != 2 {
+		t.Fatalf("Expected two clones, got %v", executor.args)
+	}
+	for _, args := range executor.args {
+		url, target := args[len(args)-2], args[len(args)-1]
+		if expected[url] != target {
+			t.Errorf("Expected %s to be cloned into %s, got %s", url, expected[url], target)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package piscator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+// Target is a user or org listed by cast or reel
+type Target struct {
+	Name  string
+	IsOrg bool
+}
+
+// Parses a target given on the command line or in a targets file. A target
+// is an org when isOrg, unless it is prefixed with user:, and org: marks a
+// single target as an org, so users and orgs can be mixed in one list.
+func ParseTarget(s string, isOrg bool) Target {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, "org:"):
+		return Target{Name: strings.TrimPrefix(s, "org:"), IsOrg: true}
+	case strings.HasPrefix(s, "user:"):
+		return Target{Name: strings.TrimPrefix(s, "user:")}
+	default:
+		return Target{Name: s, IsOrg: isOrg}
+	}
+}
+
+// Reads targets from r, one per line. Blank lines and lines starting with #
+// are skipped.
+func ReadTargets(r io.Reader) ([]string, error) {
+	var targets []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	return targets, scanner.Err()
+}
+
+// TargetRepos is the listing of one target
+type TargetRepos struct {
+	Target Target
+	Repos  []RepoModel
+	Err    error
+}
+
+// Lists every target concurrently, at most concurrentLimit at a time, and
+// returns the listings in the order of targets. Targets named twice are
+// listed once, and a repo appearing in more than one listing is only kept in
+// the first, so it's never cloned twice.
+func ListTargets(targets []Target, concurrentLimit int, list func(target Target) (string, error)) []TargetRepos {
+	var unique []Target
+	seenTarget := map[string]bool{}
+	for _, target := range targets {
+		key := strings.ToLower(target.Name)
+		if target.Name != "" && seenTarget[key] {
+			continue
+		}
+		seenTarget[key] = true
+		unique = append(unique, target)
+	}
+
+	results := make([]TargetRepos, len(unique))
+	sem := make(chan struct{}, concurrentLimit)
+	var wg sync.WaitGroup
+	for i, target := range unique {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i].Target = target
+			res, err := list(target)
+			if err == nil {
+				err = json.Unmarshal([]byte(res), &results[i].Repos)
+			}
+			if err != nil {
+				results[i].Err = fmt.Errorf("%s: %w", target.Name, err)
+			}
+		}(i, target)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for i := range results {
+		repos := []RepoModel{}
+		for _, repo := range results[i].Repos {
+			key := targetRepoKey(repo)
+			if !seen[key] {
+				seen[key] = true
+				repos = append(repos, repo)
+			}
+		}
+		results[i].Repos = repos
+	}
+	return results
+}
+
+// Repos listed for different owners can share a name, so they're keyed by id
+// or their URL instead
+func targetRepoKey(repo RepoModel) string {
+	if repo.ID != 0 {
+		return fmt.Sprintf("id:%d", repo.ID)
+	}
+	return "url:" + strings.ToLower(repo.URL)
+}
+
+// Returns the repos of every listing as one JSON list
+func MergeTargetRepos(results []TargetRepos) (string, error) {
+	repos := []RepoModel{}
+	for _, result := range results {
+		repos = append(repos, result.Repos...)
+	}
+	jsonData, err := json.MarshalIndent(repos, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(jsonData), nil
+}
+
+// Narrows every listing down to the repos in keep, a JSON list such as the one
+// PickRepos returns for the merged listings
+func KeepTargetRepos(results []TargetRepos, keep string) ([]TargetRepos, error) {
+	var kept []RepoModel
+	if err := json.Unmarshal([]byte(keep), &kept); err != nil {
+		return nil, err
+	}
+	keys := map[string]bool{}
+	for _, repo := range kept {
+		keys[targetRepoKey(repo)] = true
+	}
+
+	narrowed := make([]TargetRepos, len(results))
+	for i, result := range results {
+		narrowed[i] = TargetRepos{Target: result.Target, Err: result.Err, Repos: []RepoModel{}}
+		for _, repo := range result.Repos {
+			if keys[targetRepoKey(repo)] {
+				narrowed[i].Repos = append(narrowed[i].Repos, repo)
+			}
+		}
+	}
+	return narrowed, nil
+}
+
+// TargetSummary is how reeling one target went
+type TargetSummary struct {
+	Name   string
+	Repos  int
+	Failed int
+	Err    error
+}
+
+// Formats the combined summary of a multi target reel, one row per target
+// followed by the totals
+func FormatTargetSummary(summaries []TargetSummary) string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tREPOS\tFAILED\tERROR")
+
+	var repos, failed, targetsFailed int
+	for _, s := range summaries {
+		errMsg := ""
+		if s.Err != nil {
+			errMsg = s.Err.Error()
+			targetsFailed++
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", s.Name, s.Repos, s.Failed, errMsg)
+		repos += s.Repos
+		failed += s.Failed
+	}
+	w.Flush()
+
+	fmt.Fprintf(&b, "%d targets, %d repos, %d failed", len(summaries), repos, failed)
+	if targetsFailed > 0 {
+		fmt.Fprintf(&b, ", %d targets with errors", targetsFailed)
+	}
+	return b.String()
+}
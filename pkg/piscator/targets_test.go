@@ -0,0 +1,119 @@
+package piscator
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in    string
+		isOrg bool
+		want  Target
+	}{
+		{"acme", false, Target{Name: "acme"}},
+		{"acme", true, Target{Name: "acme", IsOrg: true}},
+		{"org:acme", false, Target{Name: "acme", IsOrg: true}},
+		{"user:octocat", true, Target{Name: "octocat"}},
+		{"  acme ", false, Target{Name: "acme"}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseTarget(tt.in, tt.isOrg); got != tt.want {
+			t.Errorf("ParseTarget(%q, %v): Expected %v, got %v", tt.in, tt.isOrg, tt.want, got)
+		}
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	got, err := ReadTargets(strings.NewReader("# teams\nacme\n\n  org:widgets  \n#user:old\nuser:octocat\n"))
+	if err != nil {
+		t.Fatalf("ReadTargets() error = %v", err)
+	}
+	want := []string{"acme", "org:widgets", "user:octocat"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestListTargets(t *testing.T) {
+	listings := map[string]string{
+		"org-a":  `[{"id": 1, "name": "api", "html_url": "https://github.com/org-a/api"}, {"id": 2, "name": "web", "html_url": "https://github.com/org-a/web"}]`,
+		"org-b":  `[{"id": 2, "name": "web", "html_url": "https://github.com/org-a/web"}, {"id": 3, "name": "api", "html_url": "https://github.com/org-b/api"}]`,
+		"user-c": `[{"name": "dotfiles", "html_url": "https://github.com/user-c/dotfiles"}]`,
+	}
+
+	var calls int32
+	results := ListTargets([]Target{{Name: "org-a", IsOrg: true}, {Name: "org-b", IsOrg: true}, {Name: "user-c"}, {Name: "Org-A", IsOrg: true}, {Name: "missing"}}, 2, func(target Target) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		res, ok := listings[target.Name]
+		if !ok {
+			return "", errors.New("not found")
+		}
+		return res, nil
+	})
+
+	if calls != 4 {
+		t.Errorf("Expected each target to be listed once, got %d listings", calls)
+	}
+
+	var got []string
+	for _, result := range results {
+		var names []string
+		for _, repo := range result.Repos {
+			names = append(names, repo.Name)
+		}
+		got = append(got, fmt.Sprintf("%s=%s", result.Target.Name, strings.Join(names, ",")))
+	}
+	want := []string{"org-a=api,web", "org-b=api", "user-c=dotfiles", "missing="}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if results[3].Err == nil || !strings.Contains(results[3].Err.Error(), "missing") {
+		t.Errorf("Expected the failed target in the error, got %v", results[3].Err)
+	}
+
+	merged, err := MergeTargetRepos(results)
+	if err != nil {
+		t.Fatalf("MergeTargetRepos() error = %v", err)
+	}
+	if strings.Count(merged, `"html_url"`) != 4 {
+		t.Errorf("Expected 4 merged repos, got %s", merged)
+	}
+}
+
+func TestKeepTargetRepos(t *testing.T) {
+	results := []TargetRepos{
+		{Target: Target{Name: "org-a"}, Repos: []RepoModel{{ID: 1, Repo: Repo{Name: "api"}}, {ID: 2, Repo: Repo{Name: "web"}}}},
+		{Target: Target{Name: "org-b"}, Repos: []RepoModel{{ID: 3, Repo: Repo{Name: "api"}}}},
+	}
+
+	got, err := KeepTargetRepos(results, `[{"id": 3, "name": "api"}, {"id": 2, "name": "web"}]`)
+	if err != nil {
+		t.Fatalf("KeepTargetRepos() error = %v", err)
+	}
+	if len(got[0].Repos) != 1 || got[0].Repos[0].ID != 2 {
+		t.Errorf("Expected only web in org-a, got %v", got[0].Repos)
+	}
+	if len(got[1].Repos) != 1 || got[1].Repos[0].ID != 3 {
+		t.Errorf("Expected api in org-b, got %v", got[1].Repos)
+	}
+}
+
+func TestFormatTargetSummary(t *testing.T) {
+	got := FormatTargetSummary([]TargetSummary{
+		{Name: "org-a", Repos: 12, Failed: 1},
+		{Name: "missing", Err: errors.New("not found")},
+	})
+
+	for _, want := range []string{"TARGET", "org-a    12     1", "not found", "2 targets, 12 repos, 1 failed, 1 targets with errors"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in summary, got\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package piscator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults used by NewRetryClient
+const (
+	DefaultMaxAttempts = 5
+	DefaultBaseDelay   = time.Second
+	DefaultMaxDelay    = time.Minute
+)
+
+// ErrUnauthorized is returned when GitHub rejects the credentials
+var ErrUnauthorized = errors.New("GitHub rejected the credentials, check your token or username/password")
+
+// ErrNotFound is returned when GitHub can't find the requested resource
+var ErrNotFound = errors.New("not found, check the user/org name and that your token can access it")
+
+// StatusError is returned for responses that won't succeed by retrying
+type StatusError struct {
+	URL        string
+	StatusCode int
+	Message    string
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	if e.Message != "" {
+		msg += " (" + e.Message + ")"
+	}
+	return msg
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// RateBudget tracks GitHub's rate limit across every request made through the
+// clients sharing it, so concurrent API calls pause together rather than each
+// discovering the limit on its own.
+type RateBudget struct {
+	mu sync.Mutex
+	// known is set once a response reported the limit, remaining counts down
+	// the requests left until reset
+	known     bool
+	remaining int
+	reset     time.Time
+	resumeAt  time.Time
+}
+
+// Reserves a request from the budget, returning how long to wait before
+// sending it. Once the requests left are spent every request waits for the
+// reset, including those already on their way when the last one went out.
+func (b *RateBudget) acquire(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.known && !now.Before(b.reset) {
+		// a new window, its limit is known again with the next response
+		b.known = false
+	}
+	if b.known && b.remaining <= 0 {
+		b.holdUntilLocked(b.reset)
+	}
+	if now.Before(b.resumeAt) {
+		return b.resumeAt.Sub(now)
+	}
+	if b.known {
+		b.remaining--
+	}
+	return 0
+}
+
+// Records the limits GitHub reported on a response
+func (b *RateBudget) update(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-Ratelimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-Ratelimit-Reset"), 10, 64)
+	if err != nil || reset <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.known, b.remaining, b.reset = true, remaining, time.Unix(reset, 0)
+	if remaining <= 0 {
+		b.holdUntilLocked(b.reset)
+	}
+}
+
+// Holds every request sharing the budget until t
+func (b *RateBudget) holdUntil(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.holdUntilLocked(t)
+}
+
+func (b *RateBudget) holdUntilLocked(t time.Time) {
+	if t.After(b.resumeAt) {
+		b.resumeAt = t
+	}
+}
+
+// Reports how long requests are held without reserving one
+func (b *RateBudget) held(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Before(b.resumeAt) {
+		return b.resumeAt.Sub(now)
+	}
+	return 0
+}
+
+// RetryClient is an HttpClient that retries failed GitHub API requests with
+// exponential backoff and jitter, honours Retry-After and both the primary and
+// secondary rate limits, and fails fast on responses retrying can't fix.
+type RetryClient struct {
+	Client      HttpClient
+	Sleeper     Sleeper
+	Observer    Observer
+	Budget      *RateBudget
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	// returns a random duration in [0, d), replaced in tests
+	jitter func(d time.Duration) time.Duration
+}
+
+// Creates a RetryClient around client with the default attempts and delays.
+// When client is already a RetryClient it is returned as is.
+func NewRetryClient(client HttpClient, sleeper Sleeper, observer Observer) *RetryClient {
+	if rc, ok := client.(*RetryClient); ok {
+		return rc
+	}
+	return &RetryClient{
+		Client:      client,
+		Sleeper:     sleeper,
+		Observer:    observer,
+		Budget:      &RateBudget{},
+		MaxAttempts: DefaultMaxAttempts,
+		BaseDelay:   DefaultBaseDelay,
+		MaxDelay:    DefaultMaxDelay,
+		jitter: func(d time.Duration) time.Duration {
+			if d <= 0 {
+				return 0
+			}
+			return time.Duration(rand.Int63n(int64(d)))
+		},
+	}
+}
+
+func (c *RetryClient) Do(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	var lastErr error
+
+	for attempt := 1; attempt <= c.MaxAttempts; attempt++ {
+		if wait := c.Budget.acquire(time.Now()); wait > 0 {
+			notify(c.Observer, RateLimited{URL: url, Until: time.Now().Add(wait), Wait: wait})
+			c.Sleeper.Sleep(wait)
+		}
+
+		attemptReq, err := rewind(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.Client.Do(attemptReq)
+		if err != nil {
+			lastErr = err
+			notify(c.Observer, RequestRetried{URL: url, Attempt: attempt, Err: err})
+			c.backoff(attempt, 0)
+			continue
+		}
+		c.Budget.update(res.Header)
+
+		if res.StatusCode < 300 || res.StatusCode == http.StatusNotModified {
+			return res, nil
+		}
+
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 64<<10))
+		res.Body.Close()
+		statusErr := &StatusError{URL: url, StatusCode: res.StatusCode, Message: githubMessage(body)}
+		lastErr = statusErr
+
+		retryAfter := parseRetryAfter(res.Header.Get("Retry-After"))
+		switch {
+		case res.StatusCode == http.StatusUnauthorized:
+			statusErr.Err = ErrUnauthorized
+			return nil, statusErr
+		case res.StatusCode == http.StatusNotFound:
+			statusErr.Err = ErrNotFound
+			return nil, statusErr
+		case isRateLimited(res, body):
+			// hold every request sharing the budget, the next attempt waits it out.
+			// Secondary limits say how long with Retry-After, primary limits were
+			// already recorded from the reset header, otherwise back off
+			wait := retryAfter
+			if wait == 0 {
+				wait = c.Budget.held(time.Now())
+			}
+			if wait == 0 {
+				wait = c.delay(attempt)
+			}
+			c.Budget.holdUntil(time.Now().Add(wait))
+			continue
+		case isRetryableStatus(res.StatusCode):
+			notify(c.Observer, RequestRetried{URL: url, Attempt: attempt, StatusCode: res.StatusCode})
+			c.backoff(attempt, retryAfter)
+			continue
+		default:
+			return nil, statusErr
+		}
+	}
+
+	return nil, fmt.Errorf("giving up after %d attempts: %w", c.MaxAttempts, lastErr)
+}
+
+// Sleeps before the next attempt, for retryAfter when GitHub asked for it or
+// else an exponentially growing delay with jitter
+func (c *RetryClient) backoff(attempt int, retryAfter time.Duration) {
+	if attempt >= c.MaxAttempts {
+		return
+	}
+	if retryAfter > 0 {
+		c.Sleeper.Sleep(retryAfter)
+		return
+	}
+	c.Sleeper.Sleep(c.delay(attempt))
+}
+
+// Exponential delay for an attempt with equal jitter, always at least half
+// the delay
+func (c *RetryClient) delay(attempt int) time.Duration {
+	delay := c.BaseDelay << (attempt - 1)
+	if delay > c.MaxDelay || delay <= 0 {
+		delay = c.MaxDelay
+	}
+	half := delay / 2
+	return half + c.jitter(delay-half)
+}
+
+// Returns the request to send for an attempt, requests with a body are
+// replayed through GetBody
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("cannot retry request with a body that can't be replayed")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = body
+	return clone, nil
+}
+
+// Primary limits exhaust X-RateLimit-Remaining, secondary limits are a 403
+// or 429 mentioning them in the body or carrying Retry-After
+func isRateLimited(res *http.Response, body []byte) bool {
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	if res.Header.Get("X-Ratelimit-Remaining") == "0" || res.Header.Get("Retry-After") != "" {
+		return true
+	}
+	lower := bytes.ToLower(body)
+	return res.StatusCode == http.StatusTooManyRequests || bytes.Contains(lower, []byte("rate limit"))
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Retry-After is either a number of seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// Extracts the message from a GitHub error body such as {"message": "Bad credentials"}
+func githubMessage(body []byte) string {
+	var ghErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &ghErr); err != nil {
+		return ""
+	}
+	return ghErr.Message
+}
@@ -0,0 +1,285 @@
+package piscator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mockResponse struct {
+	status  int
+	body    string
+	headers map[string]string
+	err     error
+}
+
+// SequenceHttpClient returns its responses in order, repeating the last one
+type SequenceHttpClient struct {
+	mu        sync.Mutex
+	responses []mockResponse
+	requests  []*http.Request
+}
+
+func (m *SequenceHttpClient) Do(req *http.Request) (*http.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := len(m.requests)
+	if i >= len(m.responses) {
+		i = len(m.responses) - 1
+	}
+	m.requests = append(m.requests, req)
+
+	r := m.responses[i]
+	if r.err != nil {
+		return nil, r.err
+	}
+	header := http.Header{}
+	for k, v := range r.headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		StatusCode: r.status,
+		Body:       io.NopCloser(strings.NewReader(r.body)),
+		Header:     header,
+	}, nil
+}
+
+func newTestRetryClient(client HttpClient, sleeper Sleeper, observer Observer) *RetryClient {
+	rc := NewRetryClient(client, sleeper, observer)
+	// no jitter so delays are predictable
+	rc.jitter = func(d time.Duration) time.Duration { return 0 }
+	return rc
+}
+
+func TestRetryClient(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+
+	tests := []struct {
+		name      string
+		responses []mockResponse
+		wantSleep []time.Duration
+		wantCalls int
+		wantErr   error
+		wantError bool
+	}{
+		{
+			name:      "success",
+			responses: []mockResponse{{status: 200, body: "[]"}},
+			wantCalls: 1,
+		},
+		{
+			name:      "unauthorized fails fast",
+			responses: []mockResponse{{status: 401, body: `{"message": "Bad credentials"}`}},
+			wantCalls: 1,
+			wantErr:   ErrUnauthorized,
+			wantError: true,
+		},
+		{
+			name:      "not found fails fast",
+			responses: []mockResponse{{status: 404, body: `{"message": "Not Found"}`}},
+			wantCalls: 1,
+			wantErr:   ErrNotFound,
+			wantError: true,
+		},
+		{
+			name:      "forbidden fails fast",
+			responses: []mockResponse{{status: 403, body: `{"message": "Resource not accessible by integration"}`}},
+			wantCalls: 1,
+			wantError: true,
+		},
+		{
+			name:      "server errors back off exponentially",
+			responses: []mockResponse{{status: 502}, {status: 503}, {status: 200, body: "[]"}},
+			wantSleep: []time.Duration{500 * time.Millisecond, time.Second},
+			wantCalls: 3,
+		},
+		{
+			name:      "network errors are retried",
+			responses: []mockResponse{{err: errors.New("connection reset")}, {status: 200, body: "[]"}},
+			wantSleep: []time.Duration{500 * time.Millisecond},
+			wantCalls: 2,
+		},
+		{
+			name:      "gives up after max attempts",
+			responses: []mockResponse{{status: 500}},
+			wantSleep: []time.Duration{500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second},
+			wantCalls: DefaultMaxAttempts,
+			wantError: true,
+		},
+		{
+			name: "retry-after is honoured",
+			responses: []mockResponse{
+				{status: 503, headers: map[string]string{"Retry-After": "7"}},
+				{status: 200, body: "[]"},
+			},
+			wantSleep: []time.Duration{7 * time.Second},
+			wantCalls: 2,
+		},
+		{
+			name: "secondary rate limit",
+			responses: []mockResponse{
+				{status: 403, body: `{"message": "You have exceeded a secondary rate limit"}`, headers: map[string]string{"Retry-After": "30"}},
+				{status: 200, body: "[]"},
+			},
+			wantSleep: []time.Duration{30 * time.Second},
+			wantCalls: 2,
+		},
+		{
+			name: "secondary rate limit without retry-after",
+			responses: []mockResponse{
+				{status: 403, body: `{"message": "You have exceeded a secondary rate limit"}`},
+				{status: 200, body: "[]"},
+			},
+			wantSleep: []time.Duration{500 * time.Millisecond},
+			wantCalls: 2,
+		},
+		{
+			name: "primary rate limit waits for reset",
+			responses: []mockResponse{
+				{status: 403, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset, 10)}},
+				{status: 200, body: "[]"},
+			},
+			wantSleep: []time.Duration{time.Until(time.Unix(reset, 0))},
+			wantCalls: 2,
+		},
+		{
+			name:      "not modified is a success",
+			responses: []mockResponse{{status: 304}},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &SequenceHttpClient{responses: tt.responses}
+			sleeper := &MockSleeper{}
+			rc := newTestRetryClient(client, sleeper, nil)
+
+			req, _ := http.NewRequest("GET", "https://api.github.com/users/shimman-dev/repos", nil)
+			res, err := rc.Do(req)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("Do() error = %v, wantError %v", err, tt.wantError)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil {
+				res.Body.Close()
+			}
+
+			if len(client.requests) != tt.wantCalls {
+				t.Errorf("Expected %d requests, got %d", tt.wantCalls, len(client.requests))
+			}
+			if len(sleeper.Durations) != len(tt.wantSleep) {
+				t.Fatalf("Expected sleeps %v, got %v", tt.wantSleep, sleeper.Durations)
+			}
+			for i, want := range tt.wantSleep {
+				got := sleeper.Durations[i]
+				if got < want-time.Second || got > want+time.Second {
+					t.Errorf("Expected sleep %d to be about %v, got %v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryClientSharedBudget(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, body: "[]", headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset, 10)}},
+		{status: 200, body: "[]"},
+	}}
+	sleeper := &MockSleeper{}
+
+	var rateLimited int
+	rc := newTestRetryClient(client, sleeper, ObserverFunc(func(event Event) {
+		if _, ok := event.(RateLimited); ok {
+			rateLimited++
+		}
+	}))
+
+	// the first call spends the last request of the budget, the second has to
+	// wait for the reset before it is sent
+	for i := 0; i < 2; i++ {
+		req, _ := http.NewRequest("GET", "https://api.github.com/users/shimman-dev/repos", nil)
+		res, err := rc.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		res.Body.Close()
+	}
+
+	if len(sleeper.Durations) != 1 || rateLimited != 1 {
+		t.Errorf("Expected a single wait for the reset, got %v sleeps and %d events", sleeper.Durations, rateLimited)
+	}
+
+	if NewRetryClient(rc, sleeper, nil) != rc {
+		t.Errorf("Expected wrapping a RetryClient to reuse it")
+	}
+}
+
+func TestRetryClientBudgetCountsDown(t *testing.T) {
+	reset := time.Now().Add(time.Minute).Unix()
+	client := &SequenceHttpClient{responses: []mockResponse{
+		{status: 200, body: "[]", headers: map[string]string{"X-RateLimit-Remaining": "1", "X-RateLimit-Reset": strconv.FormatInt(reset, 10)}},
+		{status: 200, body: "[]"},
+		{status: 200, body: "[]"},
+	}}
+	sleeper := &MockSleeper{}
+	rc := newTestRetryClient(client, sleeper, nil)
+
+	// the second call spends the last request GitHub reported, so the third
+	// waits for the reset without a response saying the budget is gone
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("GET", "https://api.github.com/users/shimman-dev/repos", nil)
+		res, err := rc.Do(req)
+		if err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+		res.Body.Close()
+		want := 0
+		if i == 2 {
+			want = 1
+		}
+		if len(sleeper.Durations) != want {
+			t.Errorf("Expected %d waits after call %d, got %v", want, i+1, sleeper.Durations)
+		}
+	}
+}
+
+func TestRetryClientReplaysBody(t *testing.T) {
+	client := &SequenceHttpClient{responses: []mockResponse{{status: 502}, {status: 200, body: "{}"}}}
+	rc := newTestRetryClient(client, &MockSleeper{}, nil)
+
+	req, _ := http.NewRequest("POST", "https://api.github.com/graphql", bytes.NewReader([]byte(`{"query": "{}"}`)))
+	res, err := rc.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	res.Body.Close()
+
+	body, _ := io.ReadAll(client.requests[1].Body)
+	if string(body) != `{"query": "{}"}` {
+		t.Errorf("Expected the body to be replayed, got %q", body)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("12"); got != 12*time.Second {
+		t.Errorf("Expected 12s, got %v", got)
+	}
+	if got := parseRetryAfter(""); got != 0 {
+		t.Errorf("Expected 0, got %v", got)
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(date); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("Expected about an hour, got %v", got)
+	}
+}
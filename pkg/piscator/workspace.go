@@ -0,0 +1,258 @@
+package piscator
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoModule is a go.mod found in a reeled repo
+type GoModule struct {
+	// Path is the module path and Dir its directory relative to the workspace
+	Path      string
+	Dir       string
+	GoVersion string
+	// Requires maps every required module path to its version
+	Requires map[string]string
+}
+
+// Skips the directories the go command ignores, and those holding copies of
+// other modules, including the worktrees branch checks out
+func skipModuleDir(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") ||
+		name == "vendor" || name == "testdata" || name == "node_modules" ||
+		strings.HasSuffix(name, worktreesSuffix)
+}
+
+// Finds every go.mod under root, ordered by directory. A workspace can only
+// use one module per path, so a fork cloned next to its upstream is an error.
+func FindGoModules(root string) ([]GoModule, error) {
+	var modules []GoModule
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != root && skipModuleDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "go.mod" {
+			return nil
+		}
+
+		module, err := parseGoMod(p)
+		if err != nil {
+			return err
+		}
+		dir, err := filepath.Rel(root, filepath.Dir(p))
+		if err != nil {
+			return err
+		}
+		module.Dir = filepath.ToSlash(dir)
+		modules = append(modules, module)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Dir < modules[j].Dir })
+
+	dirs := map[string]string{}
+	for _, module := range modules {
+		if dir, ok := dirs[module.Path]; ok {
+			return nil, fmt.Errorf("module %s is in both %s and %s, a workspace can only use one of them", module.Path, dir, module.Dir)
+		}
+		dirs[module.Path] = module.Dir
+	}
+	return modules, nil
+}
+
+// Reads the module path, go version and requirements of a go.mod
+func parseGoMod(name string) (GoModule, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return GoModule{}, err
+	}
+	defer f.Close()
+
+	module := GoModule{Requires: map[string]string{}}
+	inRequire := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case inRequire && fields[0] == ")":
+			inRequire = false
+		case inRequire && len(fields) >= 2:
+			module.Requires[unquoteModPath(fields[0])] = fields[1]
+		case fields[0] == "module" && len(fields) >= 2:
+			module.Path = unquoteModPath(fields[1])
+		case fields[0] == "go" && len(fields) >= 2:
+			module.GoVersion = fields[1]
+		case fields[0] == "require" && len(fields) >= 2 && fields[1] == "(":
+			inRequire = true
+		case fields[0] == "require" && len(fields) >= 3:
+			module.Requires[unquoteModPath(fields[1])] = fields[2]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return GoModule{}, err
+	}
+	if module.Path == "" {
+		return GoModule{}, fmt.Errorf("%s has no module directive", name)
+	}
+	return module, nil
+}
+
+func unquoteModPath(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// Compares two go versions such as 1.21 and 1.21.3
+func compareGoVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// Builds a go.work using every module. Its go version is the highest any
+// module asks for, since the workspace has to satisfy them all.
+//
+// withReplace adds a replace for every version of a workspace module that
+// another one requires, pointing it at the local copy. These take precedence
+// over replace directives in the modules' own go.mod files, which otherwise
+// conflict once the modules share a workspace.
+func GoWork(modules []GoModule, withReplace bool) string {
+	goVersion := "1.18" // the first release with workspaces
+	dirs := map[string]string{}
+	for _, module := range modules {
+		if compareGoVersions(module.GoVersion, goVersion) > 0 {
+			goVersion = module.GoVersion
+		}
+		dirs[module.Path] = workDir(module.Dir)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "go %s\n\nuse (\n", goVersion)
+	for _, module := range modules {
+		fmt.Fprintf(&b, "\t%s\n", workDir(module.Dir))
+	}
+	b.WriteString(")\n")
+
+	if withReplace {
+		replaces := map[string]bool{}
+		for _, module := range modules {
+			for path, version := range module.Requires {
+				if dir, ok := dirs[path]; ok {
+					replaces[fmt.Sprintf("%s %s => %s", path, version, dir)] = true
+				}
+			}
+		}
+		if len(replaces) > 0 {
+			lines := make([]string, 0, len(replaces))
+			for line := range replaces {
+				lines = append(lines, line)
+			}
+			sort.Strings(lines)
+			b.WriteString("\nreplace (\n")
+			for _, line := range lines {
+				fmt.Fprintf(&b, "\t%s\n", line)
+			}
+			b.WriteString(")\n")
+		}
+	}
+	return b.String()
+}
+
+// go.work paths are relative to the workspace and start with ./
+func workDir(dir string) string {
+	if dir == "." {
+		return "."
+	}
+	return "./" + dir
+}
+
+// Finds the git repositories under root, relative to it, without descending
+// into them or the worktrees branch checks out next to them
+func FindRepos(root string) ([]string, error) {
+	var repos []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || p == root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") || strings.HasSuffix(d.Name(), worktreesSuffix) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(p, ".git")); err == nil {
+			dir, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			repos = append(repos, filepath.ToSlash(dir))
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	sort.Strings(repos)
+	return repos, err
+}
+
+type codeWorkspace struct {
+	Folders  []codeWorkspaceFolder `json:"folders"`
+	Settings map[string]any        `json:"settings"`
+}
+
+type codeWorkspaceFolder struct {
+	Path string `json:"path"`
+}
+
+// Builds a VS Code .code-workspace with every repo as a folder, paths are
+// relative to the file
+func CodeWorkspace(repos []string) ([]byte, error) {
+	ws := codeWorkspace{Folders: []codeWorkspaceFolder{}, Settings: map[string]any{}}
+	for _, repo := range repos {
+		ws.Folders = append(ws.Folders, codeWorkspaceFolder{Path: repo})
+	}
+	data, err := json.MarshalIndent(ws, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
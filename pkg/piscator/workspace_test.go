@@ -0,0 +1,141 @@
+package piscator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeWorkspace(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, content := range files {
+		p := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestFindGoModules(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"api/.git/HEAD": "ref: refs/heads/main\n",
+		"api/go.mod": `module github.com/acme/api // the API
+
+go 1.21
+
+require (
+	github.com/acme/lib v1.4.0
+	github.com/spf13/cobra v1.7.0 // indirect
+)
+
+replace github.com/acme/lib => ../lib
+`,
+		"lib/.git/HEAD":             "ref: refs/heads/main\n",
+		"lib/go.mod":                "module \"github.com/acme/lib\"\n\ngo 1.22.1\n",
+		"lib/tools/go.mod":          "module github.com/acme/lib/tools\ngo 1.20\nrequire github.com/acme/lib v1.5.0\n",
+		"lib/vendor/x/go.mod":       "module x\n",
+		"lib/testdata/y/go.mod":     "module y\n",
+		"web/.git/HEAD":             "ref: refs/heads/main\n",
+		"web/package.json":          "{}\n",
+		"gists/aa11/.git/HEAD":      "ref: refs/heads/main\n",
+		"gists/aa11/notes.md":       "notes\n",
+		"api.worktrees/feat/.git":   "gitdir: ../../api/.git/worktrees/feat\n",
+		"api.worktrees/feat/go.mod": "module github.com/acme/api\n",
+	})
+
+	modules, err := FindGoModules(root)
+	if err != nil {
+		t.Fatalf("FindGoModules() error = %v", err)
+	}
+	var dirs []string
+	for _, module := range modules {
+		dirs = append(dirs, module.Dir+"="+module.Path)
+	}
+	want := []string{"api=github.com/acme/api", "lib=github.com/acme/lib", "lib/tools=github.com/acme/lib/tools"}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("Expected %v, got %v", want, dirs)
+	}
+	if modules[0].Requires["github.com/acme/lib"] != "v1.4.0" || modules[0].GoVersion != "1.21" {
+		t.Errorf("Expected the requirements and go version of api, got %+v", modules[0])
+	}
+
+	goWork := `go 1.22.1
+
+use (
+	./api
+	./lib
+	./lib/tools
+)
+`
+	if got := GoWork(modules, false); got != goWork {
+		t.Errorf("Expected\n%s\ngot\n%s", goWork, got)
+	}
+
+	withReplace := goWork + `
+replace (
+	github.com/acme/lib v1.4.0 => ./lib
+	github.com/acme/lib v1.5.0 => ./lib
+)
+`
+	if got := GoWork(modules, true); got != withReplace {
+		t.Errorf("Expected\n%s\ngot\n%s", withReplace, got)
+	}
+
+	repos, err := FindRepos(root)
+	if err != nil {
+		t.Fatalf("FindRepos() error = %v", err)
+	}
+	if want := []string{"api", "gists/aa11", "lib", "web"}; !reflect.DeepEqual(repos, want) {
+		t.Errorf("Expected %v, got %v", want, repos)
+	}
+
+	data, err := CodeWorkspace(repos)
+	if err != nil {
+		t.Fatalf("CodeWorkspace() error = %v", err)
+	}
+	want2 := "{\n  \"folders\": [\n    {\n      \"path\": \"api\"\n    },\n    {\n      \"path\": \"gists/aa11\"\n    },\n    {\n      \"path\": \"lib\"\n    },\n    {\n      \"path\": \"web\"\n    }\n  ],\n  \"settings\": {}\n}\n"
+	if string(data) != want2 {
+		t.Errorf("Expected\n%s\ngot\n%s", want2, data)
+	}
+}
+
+func TestParseGoModMissingModule(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{"go.mod": "go 1.21\n"})
+	if _, err := FindGoModules(root); err == nil {
+		t.Errorf("Expected an error for a go.mod without a module directive")
+	}
+}
+
+func TestFindGoModulesDuplicatePath(t *testing.T) {
+	root := writeWorkspace(t, map[string]string{
+		"alice/lib/go.mod": "module example.com/lib\n\ngo 1.21\n",
+		"bob/lib/go.mod":   "module example.com/lib\n\ngo 1.21\n",
+	})
+	_, err := FindGoModules(root)
+	if err == nil || !strings.Contains(err.Error(), "alice/lib") || !strings.Contains(err.Error(), "bob/lib") {
+		t.Errorf("Expected an error naming both directories, got %v", err)
+	}
+}
+
+func TestCompareGoVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.21", "1.21.0", 0},
+		{"1.21.3", "1.21", 1},
+		{"1.9", "1.18", -1},
+		{"", "1.18", -1},
+	}
+	for _, tt := range tests {
+		if got := compareGoVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareGoVersions(%q, %q): Expected %d, got %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}